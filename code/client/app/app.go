@@ -2,6 +2,7 @@ package app
 
 import (
 	rt "runtime"
+	"time"
 
 	"github.com/kardianos/service"
 	"github.com/lwch/logging"
@@ -85,6 +86,10 @@ func (a *App) run() {
 			var linkID string
 			switch msg.GetXType() {
 			case network.Msg_connect_req:
+				if a.conn.IsLameduck() {
+					a.conn.SendConnectError(msg.GetFrom(), msg.GetLinkId(), conn.ErrLameduck.Error())
+					continue
+				}
 				switch msg.GetCreq().GetXType() {
 				case network.ConnectRequest_shell:
 					a.shellCreate(mgr, a.conn, msg)
@@ -104,6 +109,10 @@ func (a *App) run() {
 		}
 	}()
 
+	if a.cfg.RemoteInterval > 0 {
+		go a.refreshConf()
+	}
+
 	if a.cfg.DashboardEnabled {
 		db := dashboard.New(a.cfg, a.conn, mgr, a.version)
 		runtime.Assert(db.ListenAndServe(a.cfg.DashboardListen, a.cfg.DashboardPort))
@@ -111,3 +120,16 @@ func (a *App) run() {
 		select {}
 	}
 }
+
+// refreshConf fetch remote configure periodically and apply it on the fly
+func (a *App) refreshConf() {
+	for {
+		time.Sleep(a.cfg.RemoteInterval)
+		cfg, err := a.cfg.Refresh()
+		if err != nil {
+			logging.Error("refresh remote configure: %v", err)
+			continue
+		}
+		a.conn.UpdateConfig(cfg)
+	}
+}
@@ -23,11 +23,17 @@ func (a *App) shellCreate(mgr *rule.Mgr, conn *conn.Conn, msg *network.Msg) {
 		}, a.cfg.ReadTimeout, a.cfg.WriteTimeout)
 		mgr.Add(tn)
 	}
-	lk := tn.NewLink(msg.GetLinkId(), msg.GetFrom(), nil, conn).(*shell.Link)
+	link, err := tn.NewLink(msg.GetLinkId(), msg.GetFrom(), nil, conn)
+	if err != nil {
+		logging.Error("create shell failed: %v", err)
+		conn.SendConnectError(msg.GetFrom(), msg.GetLinkId(), err.Error())
+		return
+	}
+	lk := link.(*shell.Link)
 	logging.Info("create link %s for shell rule [%s] from %s to %s",
 		msg.GetLinkId(), create.GetName(),
 		msg.GetFrom(), a.cfg.ID)
-	err := lk.Exec()
+	err = lk.Exec()
 	if err != nil {
 		logging.Error("create shell failed: %v", err)
 		conn.SendConnectError(msg.GetFrom(), msg.GetLinkId(), err.Error())
@@ -49,12 +55,18 @@ func (a *App) vncCreate(confDir string, mgr *rule.Mgr, conn *conn.Conn, msg *net
 		}, a.cfg.ReadTimeout, a.cfg.WriteTimeout)
 		mgr.Add(tn)
 	}
-	lk := tn.NewLink(msg.GetLinkId(), msg.GetFrom(), nil, conn).(*vnc.Link)
+	link, err := tn.NewLink(msg.GetLinkId(), msg.GetFrom(), nil, conn)
+	if err != nil {
+		logging.Error("create vnc failed: %v", err)
+		conn.SendConnectError(msg.GetFrom(), msg.GetLinkId(), err.Error())
+		return
+	}
+	lk := link.(*vnc.Link)
 	logging.Info("create link %s for vnc rule [%s] from %s to %s",
 		msg.GetLinkId(), create.GetName(),
 		msg.GetFrom(), a.cfg.ID)
 	lk.SetQuality(create.GetCvnc().GetQuality())
-	err := lk.Fork(confDir)
+	err = lk.Fork(confDir)
 	if err != nil {
 		logging.Error("create vnc failed: %v", err)
 		conn.SendConnectError(msg.GetFrom(), msg.GetLinkId(), err.Error())
@@ -0,0 +1,73 @@
+package conn
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// WriteMessageSync send message and wait for server acknowledgment,
+// returns ErrAckTimeout if not acked in timeout or ErrClosed if connection closed
+func (conn *Conn) WriteMessageSync(msg *network.Msg, timeout time.Duration) error {
+	id := atomic.AddUint64(&conn.reqID, 1)
+	ch := make(chan error, 1)
+	conn.lockAck.Lock()
+	conn.acks[id] = ch
+	conn.lockAck.Unlock()
+	defer func() {
+		conn.lockAck.Lock()
+		delete(conn.acks, id)
+		conn.lockAck.Unlock()
+	}()
+	msg.ReqId = id
+	err := conn.Send(msg)
+	if err != nil {
+		return err
+	}
+	select {
+	case err = <-ch:
+		return err
+	case <-time.After(timeout):
+		return ErrAckTimeout
+	case <-conn.ctx.Done():
+		return ErrClosed
+	}
+}
+
+func (conn *Conn) onAck(id uint64) {
+	conn.lockAck.Lock()
+	ch := conn.acks[id]
+	delete(conn.acks, id)
+	conn.lockAck.Unlock()
+	if ch != nil {
+		ch <- nil
+	}
+}
+
+// failAcks notify all waiting acknowledgments the connection was closed
+func (conn *Conn) failAcks() {
+	conn.lockAck.Lock()
+	acks := conn.acks
+	conn.acks = make(map[uint64]chan error)
+	conn.lockAck.Unlock()
+	for _, ch := range acks {
+		ch <- ErrClosed
+	}
+}
+
+// waitHandshakeAck wait for server accepted the handshake,
+// returns the max message size of server, 0 if not supported
+func waitHandshakeAck(cn *network.Conn, reqID uint64, timeout time.Duration) (uint32, error) {
+	msg, _, err := cn.ReadMessage(timeout)
+	if err != nil {
+		return 0, err
+	}
+	if msg.GetXType() == network.Msg_reject {
+		return 0, rejectError(msg)
+	}
+	if msg.GetXType() != network.Msg_ack || msg.GetReqId() != reqID {
+		return 0, errHandshakeNotAcked
+	}
+	return msg.GetHsp().GetMaxSize(), nil
+}
@@ -0,0 +1,67 @@
+package conn
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// maxReadTimeouts read timeout times before the connection is treated as dead
+	maxReadTimeouts = 60
+	// adaptiveMinDeadline must be larger than keepalive interval of server
+	adaptiveMinDeadline = 15 * time.Second
+)
+
+// ReadDeadline get the time without any message before the connection is treated as dead,
+// it is adjusted by average interval of messages in adaptive mode
+func (conn *Conn) ReadDeadline() time.Duration {
+	max := maxReadTimeouts * conn.cfg.ReadTimeout
+	if !conn.cfg.AdaptiveReadTimeout {
+		return max
+	}
+	gap := time.Duration(atomic.LoadInt64(&conn.readGap))
+	ret := 4*gap + conn.cfg.ReadTimeout
+	if ret < adaptiveMinDeadline {
+		ret = adaptiveMinDeadline
+	}
+	if ret > max {
+		ret = max
+	}
+	return ret
+}
+
+// onRead update moving average of message interval
+func (conn *Conn) onRead() {
+	now := time.Now().UnixNano()
+	last := atomic.SwapInt64(&conn.lastRead, now)
+	if last == 0 {
+		return
+	}
+	gap := now - last
+	avg := atomic.LoadInt64(&conn.readGap)
+	if avg == 0 {
+		avg = gap
+	} else {
+		avg = int64(float64(avg)*(1-scoreAlpha) + float64(gap)*scoreAlpha)
+	}
+	atomic.StoreInt64(&conn.readGap, avg)
+}
+
+// readExpired check connection is dead after read timeout times
+func (conn *Conn) readExpired(timeouts int) bool {
+	if !conn.cfg.AdaptiveReadTimeout {
+		return timeouts >= maxReadTimeouts
+	}
+	last := time.Unix(0, atomic.LoadInt64(&conn.lastRead))
+	return time.Since(last) >= conn.ReadDeadline()
+}
+
+// quietTime get the time without any message after read timeout times
+func (conn *Conn) quietTime(timeouts int) time.Duration {
+	if conn.cfg.AdaptiveReadTimeout {
+		if last := atomic.LoadInt64(&conn.lastRead); last > 0 {
+			return time.Since(time.Unix(0, last))
+		}
+	}
+	return time.Duration(timeouts) * conn.cfg.ReadTimeout
+}
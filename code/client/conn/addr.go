@@ -0,0 +1,46 @@
+package conn
+
+import (
+	"net"
+	"strings"
+)
+
+const defaultPort = "6154"
+
+// serverAddr normalize server address, supported formats:
+//
+//	host:port, ipv4:port, [ipv6]:port, [ipv6%zone]:port,
+//	host, ipv4, ipv6, [ipv6], ipv6%zone
+//
+// the default port is used when port is missing
+func serverAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err == nil {
+		return net.JoinHostPort(host, port), nil
+	}
+	if !strings.Contains(err.Error(), "missing port") &&
+		!strings.Contains(err.Error(), "too many colons") {
+		return "", err
+	}
+	host = addr
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	// host with colons must be an ipv6 address
+	if strings.Contains(host, ":") && net.ParseIP(serverName(host)) == nil {
+		return "", &net.AddrError{Err: "invalid address", Addr: addr}
+	}
+	return net.JoinHostPort(host, defaultPort), nil
+}
+
+// serverName get tls server name from server address, the ipv6 zone is removed
+func serverName(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if n := strings.IndexByte(host, '%'); n != -1 {
+		host = host[:n]
+	}
+	return host
+}
@@ -0,0 +1,59 @@
+package conn
+
+import "testing"
+
+func TestServerAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"example.com:6155", "example.com:6155"},
+		{"example.com", "example.com:6154"},
+		{"127.0.0.1:6155", "127.0.0.1:6155"},
+		{"127.0.0.1", "127.0.0.1:6154"},
+		{"[::1]:6155", "[::1]:6155"},
+		{"[::1]", "[::1]:6154"},
+		{"::1", "[::1]:6154"},
+		{"[fe80::1%eth0]:6155", "[fe80::1%eth0]:6155"},
+		{"[fe80::1%eth0]", "[fe80::1%eth0]:6154"},
+		{"fe80::1%eth0", "[fe80::1%eth0]:6154"},
+	}
+	for _, tt := range tests {
+		got, err := serverAddr(tt.addr)
+		if err != nil {
+			t.Errorf("serverAddr(%q): %v", tt.addr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("serverAddr(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestServerAddrInvalid(t *testing.T) {
+	for _, addr := range []string{"[::1", "::1]:6154", "[::1]]", "host:port:6154"} {
+		if got, err := serverAddr(addr); err == nil {
+			t.Errorf("serverAddr(%q) = %q, want error", addr, got)
+		}
+	}
+}
+
+func TestServerName(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"example.com:6154", "example.com"},
+		{"example.com", "example.com"},
+		{"127.0.0.1:6154", "127.0.0.1"},
+		{"127.0.0.1", "127.0.0.1"},
+		{"[::1]:6154", "::1"},
+		{"[fe80::1%eth0]:6154", "fe80::1"},
+		{"fe80::1%eth0", "fe80::1"},
+	}
+	for _, tt := range tests {
+		if got := serverName(tt.addr); got != tt.want {
+			t.Errorf("serverName(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package conn
+
+import (
+	"time"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// WriteBatch enqueue messages to be written back-to-back, messages of other links
+// are not interleaved between them, used by protocols with multi-frame logical units,
+// returns ErrPaused while the connection is paused
+func (conn *Conn) WriteBatch(msgs []*network.Msg) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	if other := conn.getMigrated(); other != nil {
+		return other.WriteBatch(msgs)
+	}
+	if conn.IsPaused() {
+		return ErrPaused
+	}
+	if conn.cfg.AutoRegister {
+		for _, msg := range msgs {
+			if err := conn.autoRegister(msg); err != nil {
+				return err
+			}
+		}
+	}
+	if !conn.waitBuffer(conn.cfg.WriteTimeout) {
+		return ErrBufferFull
+	}
+	batch := make([]*network.Msg, len(msgs))
+	copy(batch, msgs)
+	for _, msg := range batch {
+		conn.addPending(msg, 1)
+	}
+	unqueue := func() {
+		for _, msg := range batch {
+			conn.addPending(msg, -1)
+		}
+	}
+	select {
+	case conn.batch <- batch:
+		return nil
+	case <-time.After(conn.cfg.WriteTimeout):
+		unqueue()
+		return ErrTimeout
+	case <-conn.ctx.Done():
+		unqueue()
+		return ErrClosed
+	}
+}
+
+// startBatch returns the first message of batch and keeps the rest for nextWrite
+func (conn *Conn) startBatch(msgs []*network.Msg) *network.Msg {
+	conn.batching = msgs[1:]
+	return msgs[0]
+}
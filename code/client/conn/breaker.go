@@ -0,0 +1,130 @@
+package conn
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/lwch/natpass/code/client/global"
+)
+
+// BreakerState circuit breaker state of reconnect
+type BreakerState int
+
+const (
+	// BreakerClosed connect normally
+	BreakerClosed BreakerState = iota
+	// BreakerOpen stop connecting until cooldown
+	BreakerOpen
+	// BreakerHalfOpen probe connect after cooldown
+	BreakerHalfOpen
+)
+
+// String get breaker state name
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "Closed"
+	case BreakerOpen:
+		return "Open"
+	case BreakerHalfOpen:
+		return "HalfOpen"
+	default:
+		return "Unknown"
+	}
+}
+
+type breaker struct {
+	sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	probe     time.Duration
+	policy    global.ReconnectPolicy
+	state     BreakerState
+	failures  int
+	wait      time.Duration // current open duration
+	until     time.Time
+}
+
+func newBreaker(threshold int, cooldown, probe time.Duration, policy global.ReconnectPolicy) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		probe:     probe,
+		policy:    policy,
+	}
+}
+
+// getState get current breaker state
+func (b *breaker) getState() BreakerState {
+	b.Lock()
+	defer b.Unlock()
+	return b.state
+}
+
+// allow wait until the breaker allows next connect, returns error if ctx is done while waiting
+func (b *breaker) allow(ctx context.Context) error {
+	b.Lock()
+	if b.state != BreakerOpen {
+		b.Unlock()
+		return nil
+	}
+	d := time.Until(b.until)
+	b.Unlock()
+	if d > 0 {
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	b.Lock()
+	b.state = BreakerHalfOpen
+	b.Unlock()
+	return nil
+}
+
+// success reset the breaker after connected
+func (b *breaker) success() {
+	b.Lock()
+	defer b.Unlock()
+	b.state = BreakerClosed
+	b.failures = 0
+	b.wait = 0
+}
+
+// failure record connect failure and returns the backoff duration
+func (b *breaker) failure() time.Duration {
+	b.Lock()
+	defer b.Unlock()
+	if b.state == BreakerHalfOpen {
+		b.wait += b.probe
+		b.state = BreakerOpen
+		b.until = time.Now().Add(b.wait)
+		return 0
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.wait = b.cooldown
+		b.state = BreakerOpen
+		b.until = time.Now().Add(b.wait)
+		return 0
+	}
+	return b.backoff()
+}
+
+// backoff get delay of current failures by reconnect policy
+func (b *breaker) backoff() time.Duration {
+	p := b.policy
+	delay := float64(p.Initial) * math.Pow(p.Multiplier, float64(b.failures-1))
+	if delay > float64(p.Max) {
+		delay = float64(p.Max)
+	}
+	if p.Jitter > 0 {
+		delta := delay * float64(p.Jitter) / 100
+		delay += delta * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,62 @@
+package conn
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/lwch/natpass/code/client/global"
+)
+
+// BufferedBytes get approximate bytes of buffered messages in write queue,
+// link channels and unknown channel, the read side is estimated by average message size
+func (conn *Conn) BufferedBytes() int {
+	n := conn.PendingWriteBytes()
+	queued := len(conn.unknownRead)
+	conn.RLock()
+	for _, ch := range conn.read {
+		queued += len(ch)
+	}
+	conn.RUnlock()
+	return n + queued*int(atomic.LoadInt64(&conn.readAvg))
+}
+
+// onReadSize update moving average of received message size
+func (conn *Conn) onReadSize(size uint16) {
+	avg := atomic.LoadInt64(&conn.readAvg)
+	if avg == 0 {
+		avg = int64(size)
+	} else {
+		avg = int64(float64(avg)*(1-scoreAlpha) + float64(size)*scoreAlpha)
+	}
+	atomic.StoreInt64(&conn.readAvg, avg)
+}
+
+// bufferFull check buffered bytes exceeded max_buffer
+func (conn *Conn) bufferFull() bool {
+	max := conn.cfg.MaxBuffer.Bytes()
+	return max > 0 && uint64(conn.BufferedBytes()) >= max
+}
+
+// waitBuffer wait until buffered bytes below max_buffer by buffer_full policy,
+// returns false if the message should be dropped
+func (conn *Conn) waitBuffer(timeout time.Duration) bool {
+	if !conn.bufferFull() {
+		return true
+	}
+	if conn.cfg.BufferFullPolicy == global.BufferFullDrop {
+		return false
+	}
+	clk := conn.getClock()
+	deadline := clk.Now().Add(timeout)
+	for conn.bufferFull() {
+		if !clk.Now().Before(deadline) {
+			return false
+		}
+		select {
+		case <-clk.After(10 * time.Millisecond):
+		case <-conn.ctx.Done():
+			return false
+		}
+	}
+	return true
+}
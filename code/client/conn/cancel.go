@@ -0,0 +1,57 @@
+package conn
+
+import (
+	"sync/atomic"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+const (
+	handleQueued int32 = iota
+	handleCancelled
+	handleTaken
+)
+
+// SendHandle handle of queued message
+type SendHandle struct {
+	state int32
+}
+
+// Cancel remove message from write queue if it is not taken by the write loop,
+// returns false if the message is already written or being written
+func (h *SendHandle) Cancel() bool {
+	return atomic.CompareAndSwapInt32(&h.state, handleQueued, handleCancelled)
+}
+
+// SendCancellable send message like Send and returns a handle to cancel it before written
+func (conn *Conn) SendCancellable(msg *network.Msg) (*SendHandle, error) {
+	if other := conn.getMigrated(); other != nil {
+		return other.SendCancellable(msg)
+	}
+	h := &SendHandle{}
+	conn.lockHandle.Lock()
+	conn.handles[msg] = h
+	conn.lockHandle.Unlock()
+	err := conn.Send(msg)
+	if err != nil {
+		conn.lockHandle.Lock()
+		delete(conn.handles, msg)
+		conn.lockHandle.Unlock()
+		return nil, err
+	}
+	return h, nil
+}
+
+// cancelled check the message was cancelled, otherwise it can not be cancelled anymore
+func (conn *Conn) cancelled(msg *network.Msg) bool {
+	conn.lockHandle.Lock()
+	h, ok := conn.handles[msg]
+	if ok {
+		delete(conn.handles, msg)
+	}
+	conn.lockHandle.Unlock()
+	if !ok {
+		return false
+	}
+	return !atomic.CompareAndSwapInt32(&h.state, handleQueued, handleTaken)
+}
@@ -0,0 +1,51 @@
+package conn
+
+import (
+	"hash/crc32"
+	"sync/atomic"
+
+	"github.com/lwch/natpass/code/network"
+	"google.golang.org/protobuf/proto"
+)
+
+// checksum crc32 of message end to end, fields modified by server are excluded
+func checksum(msg *network.Msg) uint32 {
+	dup := proto.Clone(msg).(*network.Msg)
+	dup.From = ""
+	dup.To = ""
+	dup.Checksum = 0
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(dup)
+	sum := crc32.ChecksumIEEE(data)
+	if sum == 0 {
+		// 0 means not set
+		sum = 1
+	}
+	return sum
+}
+
+func needChecksum(msg *network.Msg) bool {
+	switch msg.GetXType() {
+	case network.Msg_handshake,
+		network.Msg_keepalive,
+		network.Msg_ack:
+		return false
+	}
+	return true
+}
+
+// verifyChecksum verify checksum if it was set by sender
+func (conn *Conn) verifyChecksum(msg *network.Msg) bool {
+	if msg.GetChecksum() == 0 {
+		return true
+	}
+	if checksum(msg) == msg.GetChecksum() {
+		return true
+	}
+	atomic.AddUint64(&conn.corruptCount, 1)
+	return false
+}
+
+// CorruptCount get count of messages dropped by invalid checksum
+func (conn *Conn) CorruptCount() uint64 {
+	return atomic.LoadUint64(&conn.corruptCount)
+}
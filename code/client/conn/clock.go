@@ -0,0 +1,60 @@
+package conn
+
+import "time"
+
+// Ticker ticker returned by Clock
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Clock source of time used by Conn, it can be replaced by a fake clock in tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// clockHolder keep the concrete type stored in atomic.Value consistent
+type clockHolder struct {
+	Clock
+}
+
+// SetClock replace the clock used by keepalive, drop expiry, idle check and
+// removed link cache, nil to restore the real clock
+func (conn *Conn) SetClock(c Clock) {
+	if c == nil {
+		c = realClock{}
+	}
+	conn.clock.Store(clockHolder{c})
+}
+
+func (conn *Conn) getClock() Clock {
+	h, ok := conn.clock.Load().(clockHolder)
+	if !ok {
+		return realClock{}
+	}
+	return h.Clock
+}
@@ -0,0 +1,195 @@
+package conn
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lwch/natpass/code/client/global"
+	"github.com/lwch/natpass/code/network"
+)
+
+// fakeClock clock moved only by Advance, it stands for the monotonic clock
+// so a wall clock jump of the device is an Advance of any duration
+type fakeClock struct {
+	sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock  *fakeClock
+	at     time.Time
+	period time.Duration // 0 for timer of After
+	ch     chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1600000000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	return c.add(d, 0).ch
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	return c.add(d, d)
+}
+
+func (c *fakeClock) add(d, period time.Duration) *fakeTimer {
+	c.Lock()
+	defer c.Unlock()
+	t := &fakeTimer{clock: c, at: c.now.Add(d), period: period, ch: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance move the clock and fire the timers reached, tickers fire once
+// no matter how many periods passed like time.Ticker
+func (c *fakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+	timers := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			timers = append(timers, t)
+			continue
+		}
+		select {
+		case t.ch <- c.now:
+		default:
+		}
+		if t.period > 0 {
+			for !t.at.After(c.now) {
+				t.at = t.at.Add(t.period)
+			}
+			timers = append(timers, t)
+		}
+	}
+	c.timers = timers
+}
+
+// waiters count of pending timers
+func (c *fakeClock) waiters() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.timers)
+}
+
+// waitTimers wait until n timers are pending so that Advance is observed
+func (c *fakeClock) waitTimers(t *testing.T, n int) {
+	deadline := time.Now().Add(time.Second)
+	for c.waiters() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timers pending: %d, want %d", c.waiters(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() {
+	c := t.clock
+	c.Lock()
+	defer c.Unlock()
+	for i, v := range c.timers {
+		if v == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return
+		}
+	}
+}
+
+func TestDropClockJump(t *testing.T) {
+	conn := newTestConn(t, nil)
+	clock := newFakeClock()
+	conn.SetClock(clock)
+	go conn.checkDrop()
+
+	conn.addDrop("l1")
+	clock.waitTimers(t, 1)
+	clock.Advance(dropPenalty - time.Second)
+	if !conn.isDropped("l1") {
+		t.Fatal("link released before penalty")
+	}
+
+	// jump over the penalty and reset period at once
+	clock.waitTimers(t, 1)
+	clock.Advance(time.Hour)
+	if conn.isDropped("l1") {
+		t.Fatal("link still dropped after clock jump")
+	}
+	if len(conn.DroppedLinks()) != 0 {
+		t.Fatal("dropped links not empty after clock jump")
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		conn.lockDrop.RLock()
+		n := len(conn.drop)
+		conn.lockDrop.RUnlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expired drop not removed by checkDrop")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// strikes are reset so penalty starts over
+	conn.addDrop("l1")
+	if got := conn.DroppedLinks()["l1"].Sub(clock.Now()); got != dropPenalty {
+		t.Fatalf("penalty after reset: %s, want %s", got, dropPenalty)
+	}
+}
+
+func TestKeepaliveClockJump(t *testing.T) {
+	conn := newTestConn(t, func(cfg *global.Configure) {
+		cfg.ReadTimeout = 10 * time.Millisecond
+	})
+	clock := newFakeClock()
+	conn.SetClock(clock)
+	interval := conn.KeepaliveInterval()
+	go conn.keepalive()
+
+	// a jump of many intervals sends one keepalive, not a burst
+	clock.waitTimers(t, 1)
+	clock.Advance(time.Hour)
+	msg := nextWritten(conn, time.Second)
+	if msg == nil || msg.GetXType() != network.Msg_keepalive {
+		t.Fatal("keepalive not sent after clock jump")
+	}
+	if msg := nextWritten(conn, 50*time.Millisecond); msg != nil {
+		t.Fatalf("unexpected message %s after clock jump", msg.GetXType().String())
+	}
+
+	// data in the interval is liveness while suspended
+	conn.SuspendKeepalive()
+	defer conn.ResumeKeepalive()
+	clock.waitTimers(t, 1)
+	clock.Advance(interval / 2)
+	atomic.StoreInt64(&conn.lastActive, clock.Now().UnixNano())
+	clock.Advance(interval / 2)
+	if msg := nextWritten(conn, 50*time.Millisecond); msg != nil {
+		t.Fatalf("keepalive sent with data in interval: %s", msg.GetXType().String())
+	}
+
+	// data is stale after the jump so keepalive is sent again
+	clock.waitTimers(t, 1)
+	clock.Advance(time.Hour)
+	msg = nextWritten(conn, time.Second)
+	if msg == nil || msg.GetXType() != network.Msg_keepalive {
+		t.Fatal("keepalive not sent after clock jump while suspended")
+	}
+}
@@ -0,0 +1,51 @@
+package conn
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// closedTTL time of removed link id kept to absorb in-flight messages
+	closedTTL = 30 * time.Second
+	// closedMax max count of removed link ids kept
+	closedMax = 4096
+)
+
+// addClosed remember the removed link id
+func (conn *Conn) addClosed(id string) {
+	now := conn.getClock().Now()
+	conn.lockClosed.Lock()
+	defer conn.lockClosed.Unlock()
+	if len(conn.closed) >= closedMax {
+		for id, t := range conn.closed {
+			if now.Sub(t) >= closedTTL {
+				delete(conn.closed, id)
+			}
+		}
+		if len(conn.closed) >= closedMax {
+			return
+		}
+	}
+	conn.closed[id] = now
+}
+
+// isClosed check the link was removed recently, the late messages are counted
+func (conn *Conn) isClosed(id string) bool {
+	conn.lockClosed.Lock()
+	t, ok := conn.closed[id]
+	if ok && conn.getClock().Now().Sub(t) >= closedTTL {
+		delete(conn.closed, id)
+		ok = false
+	}
+	conn.lockClosed.Unlock()
+	if ok {
+		atomic.AddUint64(&conn.lateCount, 1)
+	}
+	return ok
+}
+
+// LateCount get count of dropped messages on recently removed links
+func (conn *Conn) LateCount() uint64 {
+	return atomic.LoadUint64(&conn.lateCount)
+}
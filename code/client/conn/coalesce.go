@@ -0,0 +1,93 @@
+package conn
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// coalescer buffer shell data of link and send them in one message
+type coalescer struct {
+	sync.Mutex
+	window time.Duration
+	max    int
+	to     string
+	buf    []byte
+	timer  *time.Timer
+}
+
+// SetLinkCoalesce buffer shell data of link up to window or max bytes before sent in one
+// message, used by keystroke-style links to reduce per message overhead, 0 window to disable.
+// the data stream is not changed so the peer needs no change
+func (conn *Conn) SetLinkCoalesce(id string, window time.Duration, max int) {
+	conn.lockCoalesce.Lock()
+	c := conn.coalesce[id]
+	if window <= 0 {
+		delete(conn.coalesce, id)
+	} else {
+		conn.coalesce[id] = &coalescer{window: window, max: max}
+	}
+	conn.lockCoalesce.Unlock()
+	if c != nil {
+		conn.flushCoalesce(id, c)
+	}
+}
+
+// coalesceData buffer data of link, returns false if coalescing is disabled
+func (conn *Conn) coalesceData(to, id string, data []byte) bool {
+	conn.lockCoalesce.Lock()
+	c := conn.coalesce[id]
+	conn.lockCoalesce.Unlock()
+	if c == nil {
+		return false
+	}
+	c.Lock()
+	c.to = to
+	c.buf = append(c.buf, data...)
+	full := c.max > 0 && len(c.buf) >= c.max
+	if !full && c.timer == nil {
+		c.timer = time.AfterFunc(c.window, func() {
+			conn.flushCoalesce(id, c)
+		})
+	}
+	c.Unlock()
+	if full {
+		conn.flushCoalesce(id, c)
+	}
+	return true
+}
+
+// flushCoalesce send buffered data of link
+func (conn *Conn) flushCoalesce(id string, c *coalescer) {
+	c.Lock()
+	defer c.Unlock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if len(c.buf) == 0 {
+		return
+	}
+	var msg network.Msg
+	msg.To = c.to
+	msg.XType = network.Msg_shell_data
+	msg.LinkId = id
+	msg.Payload = &network.Msg_Sdata{
+		Sdata: &network.ShellData{
+			Data: c.buf,
+		},
+	}
+	conn.Send(&msg)
+	c.buf = nil
+}
+
+// flushLink send buffered data of link before other messages to keep the order
+func (conn *Conn) flushLink(id string) {
+	conn.lockCoalesce.Lock()
+	c := conn.coalesce[id]
+	conn.lockCoalesce.Unlock()
+	if c != nil {
+		conn.flushCoalesce(id, c)
+	}
+}
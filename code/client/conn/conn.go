@@ -1,183 +1,848 @@
 package conn
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/lwch/logging"
 	"github.com/lwch/natpass/code/client/global"
 	"github.com/lwch/natpass/code/network"
 	"github.com/lwch/natpass/code/utils"
 	"github.com/lwch/runtime"
 )
 
+// connOrder last creation order of connections
+var connOrder uint64
+
 // Conn connection
 type Conn struct {
+	unknownCount   uint64 // must be first for 64-bit atomic alignment
+	filterRejected uint64 // outbound messages rejected by filter
+	lastActive     int64  // unix nano of last non-keepalive message
+	reqID          uint64 // last request id for acknowledgment
+	corruptCount   uint64
+	dupCount       uint64
+	lateCount      uint64 // messages of recently removed links
+	staleCount     uint64 // messages dropped by ttl
+	seq            uint64 // last sequence id of sent message
+	order          uint64 // creation order, connections are locked in this order
+	pendingSize    int64  // bytes of messages in write queue
+	lastRead       int64  // unix nano of last message read on data connection
+	readAt         int64  // unix nano of last message read, not reset on reconnect
+	writeAt        int64  // unix nano of last message written, not reset on reconnect
+	readGap        int64  // moving average of read interval in nanoseconds
+	readAvg        int64  // moving average of received message size
+	keepaliveGap   int64  // keepalive interval in nanoseconds, 0 is default
+	maxLinks       int64  // max registered links, changed by UpdateConfig
+	unknownMax     int64  // max unknown link messages of each source per second
+	suspend        int32  // keepalive suspended count
+	compress       int32  // 1 if handshake is compressed
+	received       int32  // 1 if any message received on current connection
+	lameduck       int32  // 1 if new links are rejected
+	connected      int32  // 1 if the data connection is established and not closed
+	windowCount    int32  // count of links with window
+	pause          int32  // pauseNone, pausePaused or pauseResuming
+	maxSize        uint32 // max message size negotiated in handshake
 	sync.RWMutex
-	cfg         *global.Configure
-	conn        *network.Conn
-	read        map[string]chan *network.Msg // link id => channel
-	unknownRead chan *network.Msg            // read message without link
-	write       chan *network.Msg
-	lockDrop    sync.RWMutex
-	drop        map[string]time.Time
+	cfg            *global.Configure
+	conn           *network.Conn
+	ctrl           *network.Conn                       // control connection, nil if not used
+	read           map[string]chan *network.Msg        // link id => channel
+	allow          map[string]map[network.MsgType]bool // link id => allowed types
+	registry       map[string]linkOptions              // link id => options
+	codecs         map[string]Codec                    // link type => payload codec
+	onReconnect    []func(ids []string)
+	unknownRead    chan *network.Msg                // read message without link
+	write          [priorityCount]chan *network.Msg // priority => write lane
+	writeIdx       int
+	batch          chan []*network.Msg // messages written back-to-back
+	batching       []*network.Msg      // rest messages of current batch, used by loopWrite only
+	workers        []chan *network.Msg // queues of read workers, empty if read_workers is 0
+	lockResend     sync.Mutex
+	resend         map[string]*resendBuffer // link id => recently sent messages
+	lockHandle     sync.Mutex
+	handles        map[*network.Msg]*SendHandle // queued message => cancellable handle
+	lockTee        sync.Mutex
+	tees           map[string]*tee // link id => tee of inbound messages
+	linkLimit      *linkLimiter
+	lockWindow     sync.Mutex
+	windows        map[string]*window // link id => flow control window
+	lockSession    sync.Mutex
+	sessions       []string // session ids of connections, the last one is current
+	lockPause      sync.Mutex
+	heldSend       []heldMsg // messages sent while paused
+	heldRead       []heldMsg // messages received while paused
+	lockPeers      sync.Mutex
+	peers          map[string]string // link id => peer of last received message
+	lockOrder      sync.Mutex
+	orders         map[string]*linkOrder // link id => send order of concurrent senders
+	lockCoalesce   sync.Mutex
+	coalesce       map[string]*coalescer // link id => buffered shell data
+	lockTTL        sync.Mutex
+	ttls           map[*network.Msg]time.Time // queued message => expire time
+	lockClosed     sync.Mutex
+	closed         map[string]time.Time // link id => removed time
+	lockDedup      sync.Mutex
+	dedup          map[string]*dedupInfo // link id => seen sequence ids
+	lockDrop       sync.RWMutex
+	drop           map[string]*dropInfo // link id => penalty
+	breaker        *breaker
+	onUnknown      func(linkID string, msg *network.Msg)
+	lockUnknown    sync.Mutex
+	unknownStart   time.Time
+	unknownRate    map[string]int    // from => unknown messages in current second
+	offenders      map[string]uint64 // from => dropped unknown messages
+	keepaliveFn    func() *network.Msg
+	keepaliveReset chan struct{} // restart keepalive timer after interval changed
+	warmup         func(cn *network.Conn) error
+	router         func(msg *network.Msg) string
+	filter         OutboundFilter
+	onFilterReject func(linkID string, msg *network.Msg)
+	hooks          lifecycle
+	lockIdle       sync.Mutex
+	dormant        bool
+	wake           chan struct{}
+	lockTransform  sync.RWMutex
+	inbound        []Transform
+	outbound       []Transform
+	raw            atomic.Value // net.Conn of last dial
+	server         atomic.Value // server address of current connection
+	tlsState       atomic.Value // *tls.ConnectionState of current connection
+	sessionCache   tls.ClientSessionCache
+	clock          atomic.Value // clockHolder, real clock if not set
+	scores         *scores
+	rttHistory     rttHistory
+	report         metricsReport
+	counts         *typeCounts
+	msgLog         *msgLogger
+	encode         *network.Histogram
+	decode         *network.Histogram
+	compressStats  *network.CompressStats
+	pool           *network.MessagePool // nil if read_pool is disabled
+	migrated       *Conn                // links migrated to
+	onPeerMigrate  func(id, peer, server string)
+	lockReconnect  sync.Mutex
+	terminal       atomic.Value // terminalHolder of terminated connection
+	lockAck        sync.Mutex
+	acks           map[uint64]chan error // request id => ack
+	ctx            context.Context
+	cancel         context.CancelFunc
 }
 
-// New new connection
+// New new connection, retry until connected
 func New(cfg *global.Configure) *Conn {
+	conn := newConn(cfg)
+	cn, err := conn.tryConnect()
+	runtime.Assert(err)
+	conn.start(cn)
+	return conn
+}
+
+func newConn(cfg *global.Configure) *Conn {
 	conn := &Conn{
-		cfg:         cfg,
-		read:        make(map[string]chan *network.Msg),
-		unknownRead: make(chan *network.Msg, 1024),
-		write:       make(chan *network.Msg, 1024),
-		drop:        make(map[string]time.Time),
+		cfg:           cfg,
+		read:          make(map[string]chan *network.Msg),
+		allow:         make(map[string]map[network.MsgType]bool),
+		registry:      make(map[string]linkOptions),
+		codecs:        make(map[string]Codec),
+		unknownRead:   make(chan *network.Msg, 1024),
+		batch:         make(chan []*network.Msg, 64),
+		drop:          make(map[string]*dropInfo),
+		dedup:         make(map[string]*dedupInfo),
+		closed:        make(map[string]time.Time),
+		tees:          make(map[string]*tee),
+		coalesce:      make(map[string]*coalescer),
+		linkLimit:     newLinkLimiter(cfg.LinkRate, cfg.LinkBurst),
+		orders:        make(map[string]*linkOrder),
+		peers:         make(map[string]string),
+		windows:       make(map[string]*window),
+		filter:        configFilter(cfg),
+		handles:       make(map[*network.Msg]*SendHandle),
+		ttls:          make(map[*network.Msg]time.Time),
+		resend:        make(map[string]*resendBuffer),
+		unknownRate:   make(map[string]int),
+		offenders:     make(map[string]uint64),
+		acks:          make(map[uint64]chan error),
+		scores:        newScores(cfg.Servers),
+		msgLog:        newMsgLogger(cfg),
+		encode:        new(network.Histogram),
+		decode:        new(network.Histogram),
+		compressStats: new(network.CompressStats),
+		counts:        new(typeCounts),
+		sessionCache:  tls.NewLRUClientSessionCache(0),
+		breaker: newBreaker(cfg.ReconnectThreshold,
+			cfg.ReconnectCooldown, cfg.ReconnectProbe, cfg.ReconnectPolicy),
+		lastActive: time.Now().UnixNano(),
 	}
-	var err error
-	conn.conn, err = conn.tryConnect()
-	runtime.Assert(err)
+	for i := range conn.write {
+		conn.write[i] = make(chan *network.Msg, 1024)
+	}
+	conn.keepaliveReset = make(chan struct{}, 1)
+	conn.order = atomic.AddUint64(&connOrder, 1)
+	conn.maxLinks = int64(cfg.MaxLinks)
+	conn.unknownMax = int64(cfg.UnknownRate)
+	if cfg.CompressHandshake {
+		conn.compress = 1
+	}
+	if cfg.ReadPool {
+		conn.pool = network.NewMessagePool()
+	}
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+	return conn
+}
+
+// start run loops on connected connection
+func (conn *Conn) start(cn *network.Conn) {
+	conn.setConn(cn)
+	conn.emitConnect()
+	conn.startWorkers()
 	go conn.loopRead()
 	go conn.loopWrite()
 	go conn.keepalive()
 	go conn.checkDrop()
-	return conn
+	go conn.checkNetwork()
+	if conn.cfg.IdleTimeout > 0 {
+		go conn.checkIdle()
+	}
 }
 
 func (conn *Conn) connect() (*network.Conn, error) {
+	server := conn.scores.best()
+	cn, err := conn.connectTo(server, false)
+	if err == nil {
+		err = conn.runWarmup(server, cn)
+	}
+	conn.scores.connected(server, err == nil)
+	if err != nil {
+		return nil, err
+	}
+	conn.server.Store(server)
+	atomic.StoreUint32(&conn.maxSize, cn.MaxSize())
+	return cn, nil
+}
+
+func (conn *Conn) connectTo(server string, control bool) (*network.Conn, error) {
+	addr, err := serverAddr(server)
+	if err != nil {
+		conn.logError("parse server address: %v", err)
+		return nil, &ConnectError{Stage: ErrDial, Addr: server, Err: err}
+	}
+	release, err := acquireDial(conn.ctx)
+	if err != nil {
+		return nil, &ConnectError{Stage: ErrDial, Addr: addr, Err: err}
+	}
+	defer release()
+	begin := time.Now()
+	ctx, cancel := context.WithTimeout(conn.ctx, conn.cfg.HandshakeTimeout)
+	defer cancel()
 	var dial net.Conn
-	var err error
-	if conn.cfg.UseSSL {
-		dial, err = tls.Dial("tcp", conn.cfg.Server, nil)
+	if len(conn.cfg.RelayChain) > 0 {
+		dial, err = conn.dialRelay(ctx, addr)
+	} else if conn.cfg.Transport == global.TransportWebSocket {
+		dial, err = conn.dialWebSocket(ctx, addr)
 	} else {
-		dial, err = net.Dial("tcp", conn.cfg.Server)
+		dial, err = conn.dialTCP(ctx, addr)
 	}
 	if err != nil {
-		logging.Error("dial: %v", err)
 		return nil, err
 	}
 	cn := network.NewConn(dial)
-	err = writeHandshake(cn, conn.cfg)
+	cn.SetHistogram(conn.encode, conn.decode)
+	cn.SetCompressStats(conn.compressStats)
+	cn.SetMessagePool(conn.pool)
+	deadline, _ := ctx.Deadline()
+	compressed := atomic.LoadInt32(&conn.compress) == 1
+	var reqID uint64
+	if conn.cfg.HandshakeAck {
+		reqID = atomic.AddUint64(&conn.reqID, 1)
+	}
+	err = writeHandshake(cn, conn.cfg, reqID, control, compressed, time.Until(deadline))
+	var maxSize uint32
+	if err == nil && reqID > 0 {
+		maxSize, err = waitHandshakeAck(cn, reqID, time.Until(deadline))
+	}
 	if err != nil {
-		logging.Error("write handshake: %v", err)
-		return nil, err
+		cn.Close()
+		if compressed && atomic.CompareAndSwapInt32(&conn.compress, 1, 0) {
+			conn.logInfo("compressed handshake failed, fallback to uncompressed")
+		}
+		conn.logError("handshake: %v", err)
+		return nil, newConnectError(ErrHandshake, addr, err)
 	}
-	logging.Info("%s connected", conn.cfg.Server)
+	cn.SetMaxSize(network.NegotiateMaxSize(uint32(conn.cfg.MaxMessageSize.Bytes()), maxSize))
+	conn.logInfo("%s connected, max message size %d", server, cn.MaxSize())
+	conn.addHandshake(HandshakeResult{
+		Server:  server,
+		Codec:   conn.cfg.Codec,
+		Control: control,
+		TLS:     conn.cfg.UseSSL,
+		Elapsed: time.Since(begin),
+	})
 	return cn, nil
 }
 
+// tryConnect connect until succeeded, returns ErrClosed if the connection is closed or terminated
 func (conn *Conn) tryConnect() (*network.Conn, error) {
-	var ret *network.Conn
-	var err error
-	for i := 0; i < 10; i++ {
-		ret, err = conn.connect()
+	for i := 0; ; i++ {
+		if conn.ctx.Err() != nil {
+			return nil, ErrClosed
+		}
+		if conn.breaker.allow(conn.ctx) != nil {
+			return nil, ErrClosed
+		}
+		ret, err := conn.connect()
 		if err == nil {
+			conn.breaker.success()
 			return ret, nil
 		}
-		logging.Error("connect error on %d times: %v", i+1, err)
-		time.Sleep(time.Second)
+		conn.logError("connect error on %d times: %v", i+1, err)
+		if !conn.retriable(err) {
+			conn.terminate(err)
+			return nil, err
+		}
+		if max := conn.cfg.ReconnectPolicy.MaxAttempts; max > 0 && i+1 >= max {
+			err = &AttemptsError{Attempts: i + 1, Err: err}
+			conn.terminate(err)
+			return nil, err
+		}
+		backoff := conn.breaker.failure()
+		if conn.breaker.getState() == BreakerOpen {
+			conn.logError("circuit breaker opened")
+		}
+		select {
+		case <-time.After(backoff):
+		case <-conn.ctx.Done():
+			return nil, ErrClosed
+		}
+	}
+}
+
+func (conn *Conn) getConn() *network.Conn {
+	conn.RLock()
+	defer conn.RUnlock()
+	return conn.conn
+}
+
+func (conn *Conn) setConn(cn *network.Conn) {
+	conn.Lock()
+	conn.conn = cn
+	conn.Unlock()
+	conn.newSession()
+	atomic.StoreInt32(&conn.connected, 1)
+	atomic.StoreInt32(&conn.received, 0)
+	atomic.StoreInt64(&conn.lastRead, time.Now().UnixNano())
+	if conn.cfg.ControlConn {
+		conn.resetControl()
+	}
+}
+
+// reconnect replace the broken connection, skipped if it was already replaced
+func (conn *Conn) reconnect(old *network.Conn, cause error) error {
+	if conn.getConn() == old {
+		conn.emitDisconnect(old, cause)
+	}
+	var connected bool
+	defer func() {
+		if connected {
+			conn.emitConnect()
+		}
+	}()
+	conn.lockReconnect.Lock()
+	defer conn.lockReconnect.Unlock()
+	if conn.getConn() != old {
+		return nil
+	}
+	atomic.StoreInt32(&conn.connected, 0)
+	old.Close()
+	conn.failAcks()
+	if atomic.LoadInt32(&conn.received) == 0 &&
+		atomic.CompareAndSwapInt32(&conn.compress, 1, 0) {
+		conn.logInfo("no message received after compressed handshake, fallback to uncompressed")
+	}
+	cn, err := conn.tryConnect()
+	if err != nil {
+		return err
 	}
-	return nil, err
+	conn.setConn(cn)
+	connected = true
+	conn.restoreLinks()
+	return nil
 }
 
-func writeHandshake(conn *network.Conn, cfg *global.Configure) error {
+// Reconnect close current connection and connect immediately without backoff,
+// the current connection is kept if connect failed
+func (conn *Conn) Reconnect() error {
+	var old *network.Conn
+	defer func() {
+		if old != nil {
+			conn.emitDisconnect(old, nil)
+			conn.emitConnect()
+		}
+	}()
+	conn.lockReconnect.Lock()
+	defer conn.lockReconnect.Unlock()
+	cn, err := conn.connect()
+	if err != nil {
+		return err
+	}
+	conn.breaker.success()
+	old = conn.getConn()
+	conn.setConn(cn)
+	old.Close()
+	conn.failAcks()
+	conn.restoreLinks()
+	return nil
+}
+
+func writeHandshake(conn *network.Conn, cfg *global.Configure, reqID uint64,
+	control, compressed bool, timeout time.Duration) error {
 	var msg network.Msg
 	msg.XType = network.Msg_handshake
+	msg.ReqId = reqID
 	msg.From = cfg.ID
 	msg.To = "server"
 	msg.Payload = &network.Msg_Hsp{
 		Hsp: &network.HandshakePayload{
-			Enc: cfg.Enc[:],
+			Enc:      cfg.Enc[:],
+			Codec:    cfg.Codec,
+			Ext:      cfg.HandshakeExt,
+			Control:  control,
+			MaxSize:  uint32(cfg.MaxMessageSize.Bytes()),
+			Instance: instance,
 		},
 	}
-	return conn.WriteMessage(&msg, 5*time.Second)
+	var err error
+	if compressed {
+		err = conn.WriteCompressedMessage(&msg, timeout)
+	} else {
+		err = conn.WriteMessage(&msg, timeout)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetCodec(network.GetCodec(cfg.Codec))
+	return nil
 }
 
 func (conn *Conn) loopRead() {
 	defer utils.Recover("loopRead")
 	var timeout int
 	for {
-		msg, _, err := conn.conn.ReadMessage(conn.cfg.ReadTimeout)
+		cn := conn.getConn()
+		msg, size, err := cn.ReadMessage(conn.cfg.ReadTimeout)
 		if err != nil {
+			if conn.ctx.Err() != nil {
+				return
+			}
+			// replaced by Reconnect or SwitchServer
+			if conn.getConn() != cn {
+				timeout = 0
+				continue
+			}
+			if conn.waitWake() {
+				timeout = 0
+				continue
+			}
 			if strings.Contains(err.Error(), "i/o timeout") {
 				timeout++
-				if timeout >= 60 {
-					logging.Error("too many timeout times")
-					conn.conn, err = conn.tryConnect()
-					runtime.Assert(err)
+				if conn.readExpired(timeout) {
+					quiet := conn.quietTime(timeout)
+					conn.logError("too many timeout times, no message received in %s", quiet)
+					err = &DisconnectError{
+						Reason: ReasonKeepaliveTimeout,
+						Quiet:  quiet,
+						Err:    err,
+					}
+					if conn.reconnect(cn, err) != nil {
+						return
+					}
 					timeout = 0
 					continue
 				}
 				continue
 			}
-			logging.Error("read message: %v", err)
-			conn.conn, err = conn.tryConnect()
-			runtime.Assert(err)
+			if errors.Is(err, network.ErrTooLarge) {
+				conn.logError("drop message larger than %d bytes", cn.MaxSize())
+				continue
+			}
+			conn.logError("read message: %v", err)
+			if conn.reconnect(cn, err) != nil {
+				return
+			}
 			continue
 		}
 		timeout = 0
-		if msg.GetXType() == network.Msg_keepalive {
-			continue
-		}
-		logging.Debug("read message %s(%s) from %s",
+		conn.onRead()
+		conn.onReadSize(size)
+		conn.dispatch(msg)
+	}
+}
+
+// handle dispatch message read from data or control connection
+func (conn *Conn) handle(msg *network.Msg) {
+	conn.markRead()
+	first := atomic.SwapInt32(&conn.received, 1) == 0
+	conn.counts.recv(msg)
+	conn.msgLog.log(msgLogRecv, msg)
+	if !conn.verifyChecksum(msg) {
+		conn.logError("drop corrupt message %s on link %s from %s",
 			msg.GetXType().String(), msg.GetLinkId(), msg.GetFrom())
-		linkID := msg.GetLinkId()
-		conn.lockDrop.RLock()
-		_, drop := conn.drop[linkID]
-		conn.lockDrop.RUnlock()
-		if drop {
-			continue
+		return
+	}
+	msg, err := conn.transform(&conn.inbound, msg)
+	if err != nil {
+		conn.logError("transform inbound message: %v", err)
+		return
+	}
+	if msg.GetXType() == network.Msg_reject {
+		conn.onReject(msg, first)
+		return
+	}
+	if msg.GetXType() == network.Msg_ack {
+		conn.onAck(msg.GetReqId())
+		conn.Release(msg)
+		return
+	}
+	conn.active(msg)
+	if msg.GetXType() == network.Msg_keepalive {
+		conn.Release(msg)
+		return
+	}
+	if msg.GetXType() == network.Msg_config {
+		// peers may send any type of message through server
+		if msg.GetFrom() != "server" {
+			conn.logError("drop configure from %s", msg.GetFrom())
+			conn.Release(msg)
+			return
 		}
-		conn.RLock()
-		ch := conn.read[linkID]
-		conn.RUnlock()
-		if ch == nil {
-			ch = conn.unknownRead
+		conn.onConfig(msg)
+		conn.Release(msg)
+		return
+	}
+	conn.logDebug("read message %s(%s) from %s",
+		msg.GetXType().String(), msg.GetLinkId(), msg.GetFrom())
+	linkID := conn.routeKey(msg)
+	if conn.isDropped(linkID) {
+		return
+	}
+	if msg.GetXType() == network.Msg_resend {
+		conn.onResend(msg)
+		return
+	}
+	if msg.GetXType() == network.Msg_migrate {
+		conn.onMigrate(msg)
+		return
+	}
+	if conn.isDuplicate(linkID, msg.GetSeq()) {
+		conn.logDebug("drop duplicate message %s on link %s, seq=%d",
+			msg.GetXType().String(), linkID, msg.GetSeq())
+		return
+	}
+	if !conn.allowed(linkID, msg.GetXType()) {
+		conn.logError("drop disallowed message %s on link %s from %s",
+			msg.GetXType().String(), linkID, msg.GetFrom())
+		return
+	}
+	if conn.holdRead(linkID, msg) {
+		return
+	}
+	conn.deliver(linkID, msg)
+}
+
+// deliver deliver message to read channel of link
+func (conn *Conn) deliver(linkID string, msg *network.Msg) {
+	conn.teeMsg(linkID, msg)
+	conn.RLock()
+	ch := conn.read[linkID]
+	conn.RUnlock()
+	if ch != nil {
+		conn.setPeer(linkID, msg.GetFrom())
+	}
+	if ch == nil {
+		if other := conn.getMigrated(); other != nil {
+			other.RLock()
+			ch = other.read[linkID]
+			other.RUnlock()
+			if ch != nil {
+				other.setPeer(linkID, msg.GetFrom())
+			}
 		}
-		select {
-		case ch <- msg:
-		case <-time.After(conn.cfg.ReadTimeout):
-			logging.Error("drop message: %s", msg.GetXType().String())
-			conn.lockDrop.Lock()
-			conn.drop[msg.GetLinkId()] = time.Now().Add(time.Minute)
-			conn.lockDrop.Unlock()
+	}
+	if ch == nil && conn.isStaleSession(msg.GetLinkId()) {
+		conn.logDebug("drop late message %s on link %s of previous session",
+			msg.GetXType().String(), linkID)
+		return
+	}
+	if ch == nil && conn.isClosed(linkID) {
+		conn.logDebug("drop late message %s on removed link %s",
+			msg.GetXType().String(), linkID)
+		return
+	}
+	if ch == nil {
+		if !conn.unknownAllowed(msg.GetFrom()) {
+			return
 		}
+		ch = conn.unknownRead
+		conn.emitUnknown(linkID, msg)
+	}
+	if !conn.waitBuffer(conn.deliverTimeout(linkID)) {
+		conn.logError("buffer full, drop message %s on link %s",
+			msg.GetXType().String(), linkID)
+		return
+	}
+	select {
+	case ch <- msg:
+	case <-time.After(conn.deliverTimeout(linkID)):
+		conn.logError("drop message: %s", msg.GetXType().String())
+		conn.addDrop(linkID)
 	}
 }
 
 func (conn *Conn) loopWrite() {
 	defer utils.Recover("loopWrite")
+	defer conn.drainWrite()
 	for {
-		msg := <-conn.write
+		msg := conn.nextWrite()
+		if msg == nil {
+			return
+		}
+		conn.addPending(msg, -1)
+		cancelled := conn.cancelled(msg)
+		if conn.stale(msg) || cancelled {
+			continue
+		}
 		msg.From = conn.cfg.ID
-		err := conn.conn.WriteMessage(msg, conn.cfg.WriteTimeout)
+		// explicit relay priority of sender is kept
+		if msg.Prio == network.Msg_normal {
+			msg.Prio = conn.wirePriority(msg)
+		}
+		conn.route(msg)
+		if !conn.filterOutbound(msg) {
+			continue
+		}
+		if msg.Seq == 0 {
+			msg.Seq = atomic.AddUint64(&conn.seq, 1)
+			conn.recordSent(msg)
+		}
+		if conn.IsDormant() {
+			if msg.GetXType() == network.Msg_keepalive {
+				continue
+			}
+			conn.Wake()
+		}
+		msg, err := conn.transform(&conn.outbound, msg)
 		if err != nil {
-			logging.Error("write message error on %s: %v",
-				conn.cfg.ID, err)
-			conn.conn, err = conn.connect()
-			runtime.Assert(err)
+			conn.logError("transform outbound message: %v", err)
 			continue
 		}
+		conn.active(msg)
+		if !conn.allowed(msg.GetLinkId(), msg.GetXType()) {
+			conn.logError("drop disallowed message %s on link %s to %s",
+				msg.GetXType().String(), msg.GetLinkId(), msg.GetTo())
+			continue
+		}
+		if conn.cfg.Checksum && needChecksum(msg) {
+			msg.Checksum = checksum(msg)
+		}
+		conn.counts.send(msg)
+		conn.msgLog.log(msgLogSend, msg)
+		if conn.loopback(msg) {
+			continue
+		}
+		if isControl(msg) {
+			ctrl := conn.getControl()
+			if ctrl != nil {
+				err = ctrl.WriteMessage(msg, conn.cfg.WriteTimeout)
+				if err == nil {
+					conn.markWrite()
+					continue
+				}
+				conn.logError("write control message error on %s: %v",
+					conn.cfg.ID, err)
+				conn.closeControl(ctrl)
+			}
+		}
+		if !conn.writeRetry(msg) {
+			return
+		}
+	}
+}
+
+// maxWriteRetry max times of message rewritten after reconnected
+const maxWriteRetry = 3
+
+// writeRetry write message, the message is rewritten on the new connection after reconnected,
+// returns false if reconnect failed
+func (conn *Conn) writeRetry(msg *network.Msg) bool {
+	for i := 0; ; i++ {
+		cn := conn.getConn()
+		err := cn.WriteMessage(msg, conn.cfg.WriteTimeout)
+		if err == nil {
+			conn.markWrite()
+			return true
+		}
+		if errors.Is(err, network.ErrTooLarge) {
+			conn.logError("drop message %s on link %s larger than %d bytes",
+				msg.GetXType().String(), msg.GetLinkId(), cn.MaxSize())
+			return true
+		}
+		conn.logError("write message error on %s: %v",
+			conn.cfg.ID, err)
+		if conn.reconnect(cn, err) != nil {
+			return false
+		}
+		if i >= maxWriteRetry {
+			conn.logError("drop message %s on link %s after %d retries",
+				msg.GetXType().String(), msg.GetLinkId(), maxWriteRetry)
+			return true
+		}
 	}
 }
 
 func (conn *Conn) keepalive() {
 	defer utils.Recover("keepalive")
 	for {
-		time.Sleep(10 * time.Second)
-		conn.SendKeepalive()
+		clock := conn.getClock()
+		interval := conn.KeepaliveInterval()
+		select {
+		case <-clock.After(interval):
+		case <-conn.keepaliveReset:
+			continue
+		case <-conn.ctx.Done():
+			return
+		}
+		if atomic.LoadInt32(&conn.suspend) > 0 &&
+			clock.Now().Sub(time.Unix(0, atomic.LoadInt64(&conn.lastActive))) < interval {
+			continue
+		}
+		server := conn.CurrentServer()
+		start := clock.Now()
+		msg := conn.keepaliveMsg()
+		conn.attachMetrics(msg, start)
+		if conn.WriteMessageSync(msg, conn.cfg.ReadTimeout) == nil {
+			now := clock.Now()
+			rtt := now.Sub(start)
+			conn.report.rtt = rtt
+			conn.scores.rtt(server, rtt)
+			conn.rttHistory.add(RTTSample{Time: now, RTT: rtt, Server: server})
+		}
+	}
+}
+
+// defaultKeepaliveInterval interval of keepalive if not set by SetKeepaliveInterval
+const defaultKeepaliveInterval = 10 * time.Second
+
+// SetKeepaliveInterval change keepalive interval on the fly, e.g. shorten it to detect
+// dead connection faster after timeouts or rtt increased, 0 to restore default interval
+func (conn *Conn) SetKeepaliveInterval(d time.Duration) {
+	if d < 0 {
+		d = 0
 	}
+	atomic.StoreInt64(&conn.keepaliveGap, int64(d))
+	select {
+	case conn.keepaliveReset <- struct{}{}:
+	default:
+	}
+}
+
+// KeepaliveInterval get current keepalive interval
+func (conn *Conn) KeepaliveInterval() time.Duration {
+	d := time.Duration(atomic.LoadInt64(&conn.keepaliveGap))
+	if d == 0 {
+		return defaultKeepaliveInterval
+	}
+	return d
+}
+
+// SuspendKeepalive suspend keepalive while data is transferring, the data is used as liveness,
+// keepalive is still sent when no data in keepalive interval, calls must be paired with ResumeKeepalive
+func (conn *Conn) SuspendKeepalive() {
+	atomic.AddInt32(&conn.suspend, 1)
 }
 
-// AddLink attach read message
-func (conn *Conn) AddLink(id string) {
-	logging.Info("add link %s", id)
+// ResumeKeepalive resume keepalive suspended by SuspendKeepalive
+func (conn *Conn) ResumeKeepalive() {
+	atomic.AddInt32(&conn.suspend, -1)
+}
+
+// Close close connection
+func (conn *Conn) Close() {
+	conn.cancel()
+	conn.notifyEOF()
+	cn := conn.getConn()
+	cn.Close()
+	conn.emitDisconnect(cn, nil)
+	if ctrl := conn.getControl(); ctrl != nil {
+		ctrl.Close()
+	}
+}
+
+// AddLink attach read message, if types is not empty only these message types
+// and the connect/disconnect messages can be sent or received on this link,
+// returns ErrLameduck in lameduck mode, returns ErrLinkRateLimited if link_rate is exceeded
+func (conn *Conn) AddLink(id string, types ...network.MsgType) error {
+	if conn.IsLameduck() {
+		conn.logError("add link %s in lameduck", id)
+		return ErrLameduck
+	}
+	if err := conn.allowLink(id); err != nil {
+		return err
+	}
+	conn.logInfo("add link %s", id)
 	conn.Lock()
 	if _, ok := conn.read[id]; !ok {
-		conn.read[id] = make(chan *network.Msg, 10)
+		conn.read[id] = make(chan *network.Msg, linkBufferSize)
+	}
+	priority := PriorityInteractive
+	var linkType string
+	if opt, ok := conn.registry[id]; ok {
+		priority = opt.priority
+		linkType = opt.linkType
+	}
+	conn.registry[id] = linkOptions{
+		size:     linkBufferSize,
+		types:    types,
+		priority: priority,
+		linkType: linkType,
+	}
+	delete(conn.allow, id)
+	if len(types) > 0 {
+		allow := make(map[network.MsgType]bool, len(types))
+		for _, t := range types {
+			allow[t] = true
+		}
+		conn.allow[id] = allow
 	}
 	conn.Unlock()
+	return nil
+}
+
+// RemoveLink detach read message
+func (conn *Conn) RemoveLink(id string) {
+	conn.logInfo("remove link %s", id)
+	conn.Lock()
+	delete(conn.read, id)
+	delete(conn.allow, id)
+	delete(conn.registry, id)
+	conn.Unlock()
+	conn.lockDedup.Lock()
+	delete(conn.dedup, id)
+	conn.lockDedup.Unlock()
+	conn.lockResend.Lock()
+	delete(conn.resend, id)
+	conn.lockResend.Unlock()
+	conn.lockPeers.Lock()
+	delete(conn.peers, id)
+	conn.lockPeers.Unlock()
+	conn.TeeLink(id, nil, false)
+	conn.SetLinkCoalesce(id, 0, 0)
+	conn.SetLinkWindow(id, 0)
+	conn.addClosed(id)
 }
 
 // Reset reset message next read
@@ -185,38 +850,51 @@ func (conn *Conn) Reset(id string, msg *network.Msg) {
 	conn.RLock()
 	ch := conn.read[id]
 	conn.RUnlock()
+	if ch == nil {
+		conn.logError("reset message on removed link %s", id)
+		return
+	}
 	ch <- msg
 }
 
-// ChanRead get read channel from link id
+// ChanRead get read channel from link id, returns nil if link is not registered,
+// the channel is never closed, use IsEOF to check the link is done
 func (conn *Conn) ChanRead(id string) <-chan *network.Msg {
 	conn.RLock()
 	defer conn.RUnlock()
 	return conn.read[id]
 }
 
-// ChanUnknown get channel of unknown link id
-func (conn *Conn) ChanUnknown() <-chan *network.Msg {
-	return conn.unknownRead
+// ChanReadOK get read channel from link id, ok is false if link is not registered
+func (conn *Conn) ChanReadOK(id string) (<-chan *network.Msg, bool) {
+	conn.RLock()
+	defer conn.RUnlock()
+	ch, ok := conn.read[id]
+	return ch, ok
 }
 
-func (conn *Conn) checkDrop() {
-	for {
-		time.Sleep(time.Second)
-
-		drops := make([]string, 0, len(conn.drop))
-		conn.lockDrop.RLock()
-		for k, t := range conn.drop {
-			if time.Now().After(t) {
-				drops = append(drops, k)
-			}
-		}
-		conn.lockDrop.RUnlock()
+// Release return message read from ChanRead to pool when read_pool is enabled,
+// the message must not be used after released, it is a no-op if read_pool is disabled
+func (conn *Conn) Release(msg *network.Msg) {
+	conn.pool.Release(msg)
+}
 
-		conn.lockDrop.Lock()
-		for _, id := range drops {
-			delete(conn.drop, id)
-		}
-		conn.lockDrop.Unlock()
+// TFOUsed check tcp fast open was used in the last dial,
+// it is only supported on linux with net.ipv4.tcp_fastopen enabled
+func (conn *Conn) TFOUsed() bool {
+	c, ok := conn.raw.Load().(net.Conn)
+	if !ok {
+		return false
 	}
+	return tfoUsed(c)
+}
+
+// BreakerState get circuit breaker state of reconnect
+func (conn *Conn) BreakerState() BreakerState {
+	return conn.breaker.getState()
+}
+
+// ChanUnknown get channel of unknown link id
+func (conn *Conn) ChanUnknown() <-chan *network.Msg {
+	return conn.unknownRead
 }
@@ -0,0 +1,25 @@
+package conn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lwch/natpass/code/client/global"
+)
+
+// newTestConn create connection without connecting to server,
+// fn changes the configure before created
+func newTestConn(t testing.TB, fn func(cfg *global.Configure)) *Conn {
+	cfg := &global.Configure{
+		ID:           "me",
+		Servers:      []string{"127.0.0.1:6154"},
+		ReadTimeout:  time.Second,
+		WriteTimeout: time.Second,
+	}
+	if fn != nil {
+		fn(cfg)
+	}
+	conn := newConn(cfg)
+	t.Cleanup(conn.cancel)
+	return conn
+}
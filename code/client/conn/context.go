@@ -0,0 +1,37 @@
+package conn
+
+import "context"
+
+type contextKey int
+
+const contextKeyConn contextKey = iota
+
+// Context get context of connection, it is cancelled when connection closed,
+// use FromContext, ClientID and ServerAddr to get connection info
+func (conn *Conn) Context() context.Context {
+	return context.WithValue(conn.ctx, contextKeyConn, conn)
+}
+
+// FromContext get connection from context returned by Context
+func FromContext(ctx context.Context) (*Conn, bool) {
+	conn, ok := ctx.Value(contextKeyConn).(*Conn)
+	return conn, ok
+}
+
+// ClientID get client id of connection in context
+func ClientID(ctx context.Context) string {
+	conn, ok := FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return conn.cfg.ID
+}
+
+// ServerAddr get current server address of connection in context
+func ServerAddr(ctx context.Context) string {
+	conn, ok := FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return conn.CurrentServer()
+}
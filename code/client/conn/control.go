@@ -0,0 +1,82 @@
+package conn
+
+import (
+	"strings"
+
+	"github.com/lwch/natpass/code/network"
+	"github.com/lwch/natpass/code/utils"
+)
+
+// isControl check message should be sent on control connection
+func isControl(msg *network.Msg) bool {
+	switch msg.GetXType() {
+	case network.Msg_keepalive,
+		network.Msg_ack,
+		network.Msg_connect_req,
+		network.Msg_connect_rep,
+		network.Msg_disconnect,
+		network.Msg_config,
+		network.Msg_config_ack,
+		network.Msg_migrate:
+		return true
+	}
+	return false
+}
+
+func (conn *Conn) getControl() *network.Conn {
+	conn.RLock()
+	defer conn.RUnlock()
+	return conn.ctrl
+}
+
+// resetControl close current control connection and connect a new one to current server,
+// the control messages are sent on data connection when connect failed
+func (conn *Conn) resetControl() {
+	conn.closeControl(conn.getControl())
+	cn, err := conn.connectTo(conn.CurrentServer(), true)
+	if err != nil {
+		conn.logError("connect control connection: %v", err)
+		return
+	}
+	conn.Lock()
+	conn.ctrl = cn
+	conn.Unlock()
+	go conn.loopReadControl(cn)
+}
+
+// closeControl close the control connection if it is still in use
+func (conn *Conn) closeControl(cn *network.Conn) {
+	if cn == nil {
+		return
+	}
+	conn.Lock()
+	if conn.ctrl == cn {
+		conn.ctrl = nil
+	}
+	conn.Unlock()
+	cn.Close()
+}
+
+func (conn *Conn) loopReadControl(cn *network.Conn) {
+	defer utils.Recover("loopReadControl")
+	defer conn.closeControl(cn)
+	var timeout int
+	for {
+		msg, _, err := cn.ReadMessage(conn.cfg.ReadTimeout)
+		if err != nil {
+			if conn.ctx.Err() != nil || conn.getControl() != cn {
+				return
+			}
+			if strings.Contains(err.Error(), "i/o timeout") {
+				timeout++
+				if timeout < 60 {
+					continue
+				}
+			}
+			conn.logError("read control message: %v", err)
+			return
+		}
+		timeout = 0
+		conn.handle(msg)
+	}
+}
@@ -0,0 +1,46 @@
+package conn
+
+import (
+	"sync/atomic"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// maxMsgType message types larger than this are counted as unknown
+const maxMsgType = 64
+
+// typeCounts message count of each type, allocated separately for 64-bit atomic alignment
+type typeCounts struct {
+	in  [maxMsgType]uint64
+	out [maxMsgType]uint64
+}
+
+func countIndex(t network.MsgType) int {
+	if t < 0 || t >= maxMsgType {
+		return int(network.Msg_unknown)
+	}
+	return int(t)
+}
+
+func (c *typeCounts) recv(msg *network.Msg) {
+	atomic.AddUint64(&c.in[countIndex(msg.GetXType())], 1)
+}
+
+func (c *typeCounts) send(msg *network.Msg) {
+	atomic.AddUint64(&c.out[countIndex(msg.GetXType())], 1)
+}
+
+// MessageTypeCounts get received and sent message count of each type
+func (conn *Conn) MessageTypeCounts() (in, out map[network.MsgType]uint64) {
+	in = make(map[network.MsgType]uint64)
+	out = make(map[network.MsgType]uint64)
+	for i := 0; i < maxMsgType; i++ {
+		if n := atomic.LoadUint64(&conn.counts.in[i]); n > 0 {
+			in[network.MsgType(i)] = n
+		}
+		if n := atomic.LoadUint64(&conn.counts.out[i]); n > 0 {
+			out[network.MsgType(i)] = n
+		}
+	}
+	return in, out
+}
@@ -0,0 +1,89 @@
+package conn
+
+import "sync/atomic"
+
+const (
+	// dedupWindow number of sequence ids tracked on each link,
+	// older sequence ids are treated as duplicate like late retransmits
+	dedupWindow = 1024
+	// dedupRestartRun consecutive sequence ids older than the window
+	// which mean the peer is restarted and its sequence ids start over
+	dedupRestartRun = 16
+)
+
+type dedupInfo struct {
+	max   uint64
+	seen  map[uint64]struct{}
+	stale int // consecutive sequence ids older than the window
+}
+
+// duplicate check and record the sequence id, the window is reset when the peer
+// is restarted: the sequence id starts over in the first window or a sustained
+// run of old sequence ids, reset is true if the window is reset
+func (info *dedupInfo) duplicate(seq uint64) (dup, reset bool) {
+	if info.max >= dedupWindow && seq <= info.max-dedupWindow {
+		info.stale++
+		if seq > dedupWindow && info.stale < dedupRestartRun {
+			return true, false
+		}
+		info.max = seq
+		info.seen = map[uint64]struct{}{seq: {}}
+		info.stale = 0
+		return false, true
+	}
+	info.stale = 0
+	if _, ok := info.seen[seq]; ok {
+		return true, false
+	}
+	info.seen[seq] = struct{}{}
+	if seq > info.max {
+		info.max = seq
+		if len(info.seen) > 2*dedupWindow {
+			for s := range info.seen {
+				if s+dedupWindow <= info.max {
+					delete(info.seen, s)
+				}
+			}
+		}
+	}
+	return false, false
+}
+
+// EnableDedup drop duplicate messages on the link by sequence id,
+// it is disabled when the link is removed
+func (conn *Conn) EnableDedup(id string) {
+	conn.lockDedup.Lock()
+	defer conn.lockDedup.Unlock()
+	if _, ok := conn.dedup[id]; ok {
+		return
+	}
+	conn.dedup[id] = &dedupInfo{seen: make(map[uint64]struct{})}
+}
+
+// DuplicateCount get count of dropped duplicate messages
+func (conn *Conn) DuplicateCount() uint64 {
+	return atomic.LoadUint64(&conn.dupCount)
+}
+
+func (conn *Conn) isDuplicate(id string, seq uint64) bool {
+	if seq == 0 {
+		return false
+	}
+	conn.lockDedup.Lock()
+	info := conn.dedup[id]
+	if info == nil {
+		conn.lockDedup.Unlock()
+		return false
+	}
+	max := info.max
+	dup, reset := info.duplicate(seq)
+	conn.lockDedup.Unlock()
+	if reset {
+		conn.logInfo("sequence id of link %s regressed from %d to %d, dedup window reset",
+			id, max, seq)
+	}
+	if dup {
+		atomic.AddUint64(&conn.dupCount, 1)
+	}
+	return dup
+}
@@ -0,0 +1,74 @@
+package conn
+
+import "testing"
+
+func TestDedup(t *testing.T) {
+	conn := newTestConn(t, nil)
+	conn.EnableDedup("l1")
+	if conn.isDuplicate("l1", 1) || conn.isDuplicate("l1", 3) {
+		t.Fatal("first seen sequence id is duplicate")
+	}
+	if !conn.isDuplicate("l1", 1) || !conn.isDuplicate("l1", 3) {
+		t.Fatal("repeated sequence id is not duplicate")
+	}
+	if conn.isDuplicate("l1", 2) {
+		t.Fatal("reordered sequence id is duplicate")
+	}
+	if conn.isDuplicate("l2", 1) || conn.isDuplicate("l2", 1) {
+		t.Fatal("duplicate on link without dedup")
+	}
+	if conn.DuplicateCount() != 2 {
+		t.Fatalf("unexpected duplicate count: %d", conn.DuplicateCount())
+	}
+}
+
+// receive accept sequence ids in [from, to] on link
+func receive(t *testing.T, conn *Conn, id string, from, to uint64) {
+	for seq := from; seq <= to; seq++ {
+		if conn.isDuplicate(id, seq) {
+			t.Fatalf("seq %d is duplicate", seq)
+		}
+	}
+}
+
+func TestDedupPeerRestart(t *testing.T) {
+	conn := newTestConn(t, nil)
+	conn.EnableDedup("l1")
+	receive(t, conn, "l1", 1, 3*dedupWindow)
+
+	// a late retransmit older than the window keeps the history
+	if !conn.isDuplicate("l1", dedupWindow+5) {
+		t.Fatal("late retransmit is not duplicate")
+	}
+	if !conn.isDuplicate("l1", 3*dedupWindow) {
+		t.Fatal("duplicate in window delivered after late retransmit")
+	}
+
+	// sequence ids of the restarted peer start over
+	receive(t, conn, "l1", 1, 10)
+	if !conn.isDuplicate("l1", 5) {
+		t.Fatal("repeated sequence id after restart is not duplicate")
+	}
+	if conn.DuplicateCount() != 3 {
+		t.Fatalf("unexpected duplicate count: %d", conn.DuplicateCount())
+	}
+}
+
+func TestDedupPeerRestartRun(t *testing.T) {
+	conn := newTestConn(t, nil)
+	conn.EnableDedup("l1")
+	receive(t, conn, "l1", 1, 4*dedupWindow)
+
+	// the restarted peer sent messages on other links before this one,
+	// the window is reset after a sustained run of old sequence ids
+	base := uint64(2 * dedupWindow)
+	for i := uint64(1); i < dedupRestartRun; i++ {
+		if !conn.isDuplicate("l1", base+i) {
+			t.Fatalf("seq %d is not duplicate before restart detected", base+i)
+		}
+	}
+	receive(t, conn, "l1", base+dedupRestartRun, base+dedupRestartRun+10)
+	if !conn.isDuplicate("l1", base+dedupRestartRun+10) {
+		t.Fatal("repeated sequence id after restart is not duplicate")
+	}
+}
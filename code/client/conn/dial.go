@@ -0,0 +1,61 @@
+package conn
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+
+	"github.com/lwch/natpass/code/client/global"
+)
+
+// UnreachableError server can not be connected, the message is readable by end users
+type UnreachableError struct {
+	Reason string
+	Err    error
+}
+
+func (e *UnreachableError) Error() string {
+	return e.Reason
+}
+
+// Unwrap returns the cause
+func (e *UnreachableError) Unwrap() error {
+	return e.Err
+}
+
+// Dial connect to server once without retry, returns UnreachableError if failed
+func Dial(cfg *global.Configure) (*Conn, error) {
+	conn := newConn(cfg)
+	server := conn.scores.best()
+	cn, err := conn.connect()
+	if err != nil {
+		conn.cancel()
+		return nil, unreachable(server, err)
+	}
+	conn.breaker.success()
+	conn.start(cn)
+	return conn, nil
+}
+
+func unreachable(server string, err error) error {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var reason string
+	switch {
+	case errors.Is(err, ErrTLS):
+		reason = "TLS handshake with " + server + " failed, please check the ssl option and server certificate"
+	case errors.As(err, &dnsErr):
+		reason = "can not resolve server address " + server + ", please check the server option and DNS settings"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		reason = "connection to " + server + " refused, please check the server is running and the port is correct"
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		reason = "connect to " + server + " timeout, please check the network and firewall"
+	case errors.Is(err, ErrHandshake):
+		reason = "handshake with " + server + " failed, please check the secret option"
+	default:
+		reason = "can not connect to " + server + ": " + err.Error()
+	}
+	return &UnreachableError{Reason: reason, Err: err}
+}
@@ -0,0 +1,39 @@
+package conn
+
+import (
+	"context"
+	"sync"
+)
+
+var dialLimit struct {
+	sync.RWMutex
+	sem chan struct{}
+}
+
+// SetMaxConcurrentDials limit count of connections in dial and handshake phase in this process,
+// used to stagger reconnects when many Conn are disconnected at once, 0 means unlimited
+func SetMaxConcurrentDials(n int) {
+	dialLimit.Lock()
+	defer dialLimit.Unlock()
+	if n <= 0 {
+		dialLimit.sem = nil
+		return
+	}
+	dialLimit.sem = make(chan struct{}, n)
+}
+
+// acquireDial wait for dial slot, returns release function
+func acquireDial(ctx context.Context) (func(), error) {
+	dialLimit.RLock()
+	sem := dialLimit.sem
+	dialLimit.RUnlock()
+	if sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,153 @@
+package conn
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lwch/natpass/code/client/global"
+)
+
+// listenDrop listen on random port, the accepted connections are closed immediately
+func listenDrop(t *testing.T) (string, *int32) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	var accepted int32
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&accepted, 1)
+			c.Close()
+		}
+	}()
+	return l.Addr().String(), &accepted
+}
+
+func TestConnectDropped(t *testing.T) {
+	for _, ssl := range []bool{false, true} {
+		addr, _ := listenDrop(t)
+		conn := newTestConn(t, func(cfg *global.Configure) {
+			cfg.Servers = []string{addr}
+			cfg.HandshakeTimeout = time.Second
+			cfg.HandshakeAck = true
+			cfg.UseSSL = ssl
+		})
+		_, err := conn.connectTo(addr, false)
+		if !errors.Is(err, ErrDropped) {
+			t.Fatalf("ssl=%v: want ErrDropped, got %v", ssl, err)
+		}
+		if !conn.retriable(err) {
+			t.Fatalf("ssl=%v: dropped connection not retriable", ssl)
+		}
+	}
+}
+
+func TestTryConnectRetriesDropped(t *testing.T) {
+	addr, accepted := listenDrop(t)
+	conn := newTestConn(t, func(cfg *global.Configure) {
+		cfg.Servers = []string{addr}
+		cfg.HandshakeTimeout = time.Second
+		cfg.HandshakeAck = true
+		// dropped errors are retried even if the stage is not configured
+		cfg.RetryOn = []string{global.RetryDial}
+		cfg.ReconnectThreshold = 100
+		cfg.ReconnectPolicy = global.ReconnectPolicy{
+			Initial:    10 * time.Millisecond,
+			Multiplier: 1,
+			Max:        10 * time.Millisecond,
+		}
+	})
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.tryConnect()
+		done <- err
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(accepted) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(accepted); n < 3 {
+		t.Fatalf("not retried after dropped, %d attempts", n)
+	}
+	conn.cancel()
+	if err := <-done; err != ErrClosed {
+		t.Fatalf("want ErrClosed, got %v", err)
+	}
+	if err := conn.TerminalError(); err != nil {
+		t.Fatalf("terminated by dropped connection: %v", err)
+	}
+}
+
+// listenSilent listen on random port, the accepted connections are kept open without reply
+func listenSilent(t *testing.T) (string, *int32) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var accepted int32
+	var conns []net.Conn
+	var lock sync.Mutex
+	t.Cleanup(func() {
+		l.Close()
+		lock.Lock()
+		for _, c := range conns {
+			c.Close()
+		}
+		lock.Unlock()
+	})
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&accepted, 1)
+			lock.Lock()
+			conns = append(conns, c)
+			lock.Unlock()
+		}
+	}()
+	return l.Addr().String(), &accepted
+}
+
+func TestHandshakeTimeoutNotRetried(t *testing.T) {
+	addr, accepted := listenSilent(t)
+	conn := newTestConn(t, func(cfg *global.Configure) {
+		cfg.Servers = []string{addr}
+		cfg.HandshakeTimeout = 100 * time.Millisecond
+		cfg.HandshakeAck = true
+		cfg.RetryOn = []string{global.RetryDial}
+		cfg.ReconnectThreshold = 100
+		cfg.ReconnectPolicy = global.ReconnectPolicy{
+			Initial:    10 * time.Millisecond,
+			Multiplier: 1,
+			Max:        10 * time.Millisecond,
+		}
+	})
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.tryConnect()
+		done <- err
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("handshake timeout retried, %d attempts", atomic.LoadInt32(accepted))
+	}
+	if !errors.Is(err, ErrHandshake) || errors.Is(err, ErrDropped) {
+		t.Fatalf("want handshake error, got %v", err)
+	}
+	if n := atomic.LoadInt32(accepted); n != 1 {
+		t.Fatalf("handshake timeout retried, %d attempts", n)
+	}
+}
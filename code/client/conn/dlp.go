@@ -0,0 +1,122 @@
+package conn
+
+import (
+	"bytes"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/lwch/natpass/code/client/global"
+	"github.com/lwch/natpass/code/network"
+)
+
+// FilterAction result of outbound filter
+type FilterAction int
+
+const (
+	// FilterPass write the message
+	FilterPass FilterAction = iota
+	// FilterRedact write the message redacted in place by filter
+	FilterRedact
+	// FilterReject drop the message and notify the link by OnFilterReject
+	FilterReject
+)
+
+// OutboundFilter inspect outbound message before written, it can redact the payload in place
+type OutboundFilter func(msg *network.Msg) FilterAction
+
+// SetOutboundFilter set filter of outbound messages for data-loss prevention,
+// the filter is applied in write loop before the message is sequenced, nil to disable
+func (conn *Conn) SetOutboundFilter(fn OutboundFilter) {
+	conn.Lock()
+	conn.filter = fn
+	conn.Unlock()
+}
+
+// OnFilterReject set callback when outbound message on link is rejected by filter
+func (conn *Conn) OnFilterReject(fn func(linkID string, msg *network.Msg)) {
+	conn.Lock()
+	conn.onFilterReject = fn
+	conn.Unlock()
+}
+
+// FilterRejected get count of outbound messages rejected by filter
+func (conn *Conn) FilterRejected() uint64 {
+	return atomic.LoadUint64(&conn.filterRejected)
+}
+
+// filterOutbound apply outbound filter, returns false if the message is rejected
+func (conn *Conn) filterOutbound(msg *network.Msg) bool {
+	conn.RLock()
+	fn := conn.filter
+	onReject := conn.onFilterReject
+	conn.RUnlock()
+	if fn == nil {
+		return true
+	}
+	switch fn(msg) {
+	case FilterRedact:
+		conn.logDebug("redact message %s on link %s", msg.GetXType().String(), msg.GetLinkId())
+	case FilterReject:
+		atomic.AddUint64(&conn.filterRejected, 1)
+		conn.logError("reject message %s on link %s by outbound filter",
+			msg.GetXType().String(), msg.GetLinkId())
+		if onReject != nil {
+			onReject(msg.GetLinkId(), msg)
+		}
+		return false
+	}
+	return true
+}
+
+// PatternFilter get outbound filter matching forward, shell and clipboard data by patterns,
+// the matched data is replaced by * in redact action
+func PatternFilter(patterns []*regexp.Regexp, redact bool) OutboundFilter {
+	return func(msg *network.Msg) FilterAction {
+		var data []byte
+		var set func([]byte)
+		switch payload := msg.GetPayload().(type) {
+		case *network.Msg_XData:
+			data = payload.XData.GetData()
+			set = func(b []byte) { payload.XData.Data = b }
+		case *network.Msg_Sdata:
+			data = payload.Sdata.GetData()
+			set = func(b []byte) { payload.Sdata.Data = b }
+		case *network.Msg_Vclipboard:
+			data = []byte(payload.Vclipboard.GetData())
+			set = func(b []byte) {
+				payload.Vclipboard.Payload = &network.VncClipboard_Data{Data: string(b)}
+			}
+		default:
+			return FilterPass
+		}
+		action := FilterPass
+		for _, re := range patterns {
+			if !re.Match(data) {
+				continue
+			}
+			if !redact {
+				return FilterReject
+			}
+			data = re.ReplaceAllFunc(data, func(b []byte) []byte {
+				return bytes.Repeat([]byte("*"), len(b))
+			})
+			action = FilterRedact
+		}
+		if action == FilterRedact {
+			set(data)
+		}
+		return action
+	}
+}
+
+// configFilter get outbound filter of dlp configure, nil if no pattern
+func configFilter(cfg *global.Configure) OutboundFilter {
+	if len(cfg.DLPPatterns) == 0 {
+		return nil
+	}
+	patterns := make([]*regexp.Regexp, 0, len(cfg.DLPPatterns))
+	for _, pattern := range cfg.DLPPatterns {
+		patterns = append(patterns, regexp.MustCompile(pattern))
+	}
+	return PatternFilter(patterns, cfg.DLPAction == global.DLPRedact)
+}
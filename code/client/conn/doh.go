@@ -0,0 +1,150 @@
+package conn
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+)
+
+var errDNSFormat = errors.New("invalid dns message")
+
+// resolve resolve host of address by DNS-over-HTTPS if configured,
+// returns the address with ip, the system dns is used if not configured
+func (conn *Conn) resolve(ctx context.Context, addr string) (string, error) {
+	if len(conn.cfg.DoH) == 0 {
+		return addr, nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return addr, nil
+	}
+	for _, t := range []uint16{dnsTypeA, dnsTypeAAAA} {
+		ips, err := dohQuery(ctx, conn.cfg.DoH, host, t)
+		if err != nil {
+			return "", fmt.Errorf("resolve %s by doh: %v", host, err)
+		}
+		if len(ips) > 0 {
+			return net.JoinHostPort(ips[0].String(), port), nil
+		}
+	}
+	return "", fmt.Errorf("resolve %s by doh: no address", host)
+}
+
+// dohQuery query ip addresses of host by RFC 8484
+func dohQuery(ctx context.Context, endpoint, host string, t uint16) ([]net.IP, error) {
+	query, err := dnsQuery(host, t)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	rep, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rep.Body.Close()
+	if rep.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status %d", rep.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(rep.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	return dnsAnswers(data, t)
+}
+
+// dnsQuery build dns query message with recursion desired
+func dnsQuery(host string, t uint16) ([]byte, error) {
+	var buf bytes.Buffer
+	// id=0 is recommended by RFC 8484 for caching, flags=RD, qdcount=1
+	buf.Write([]byte{0, 0, 1, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid host %s", host)
+		}
+		buf.WriteByte(byte(len(label)))
+		buf.WriteString(label)
+	}
+	buf.WriteByte(0)
+	binary.Write(&buf, binary.BigEndian, t)
+	binary.Write(&buf, binary.BigEndian, uint16(1)) // class IN
+	return buf.Bytes(), nil
+}
+
+// skipName skip domain name in message, returns offset after name
+func skipName(data []byte, off int) (int, error) {
+	for {
+		if off >= len(data) {
+			return 0, errDNSFormat
+		}
+		n := int(data[off])
+		switch {
+		case n == 0:
+			return off + 1, nil
+		case n&0xc0 == 0xc0: // compression pointer
+			return off + 2, nil
+		default:
+			off += n + 1
+		}
+	}
+}
+
+// dnsAnswers get ip addresses of type t from answers of dns response
+func dnsAnswers(data []byte, t uint16) ([]net.IP, error) {
+	if len(data) < 12 {
+		return nil, errDNSFormat
+	}
+	if rcode := data[3] & 0xf; rcode != 0 {
+		return nil, fmt.Errorf("dns rcode %d", rcode)
+	}
+	qd := int(binary.BigEndian.Uint16(data[4:]))
+	an := int(binary.BigEndian.Uint16(data[6:]))
+	off := 12
+	var err error
+	for i := 0; i < qd; i++ {
+		off, err = skipName(data, off)
+		if err != nil {
+			return nil, err
+		}
+		off += 4
+	}
+	var ret []net.IP
+	for i := 0; i < an; i++ {
+		off, err = skipName(data, off)
+		if err != nil {
+			return nil, err
+		}
+		if off+10 > len(data) {
+			return nil, errDNSFormat
+		}
+		rt := binary.BigEndian.Uint16(data[off:])
+		size := int(binary.BigEndian.Uint16(data[off+8:]))
+		off += 10
+		if off+size > len(data) {
+			return nil, errDNSFormat
+		}
+		if rt == t && (size == net.IPv4len || size == net.IPv6len) {
+			ret = append(ret, net.IP(append([]byte(nil), data[off:off+size]...)))
+		}
+		off += size
+	}
+	return ret, nil
+}
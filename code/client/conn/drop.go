@@ -0,0 +1,94 @@
+package conn
+
+import "time"
+
+const (
+	dropPenalty    = time.Minute
+	maxDropPenalty = 16 * time.Minute
+	// strikes are reset after no drop for this duration since released
+	dropResetAfter = 10 * time.Minute
+)
+
+// dropInfo penalty of link, elapsed time is measured from the monotonic clock
+// of start so that wall clock jumps do not affect the expiry
+type dropInfo struct {
+	strikes int
+	start   time.Time
+	penalty time.Duration
+}
+
+func (info *dropInfo) dropping(now time.Time) bool {
+	return now.Sub(info.start) < info.penalty
+}
+
+func (info *dropInfo) expired(now time.Time) bool {
+	return now.Sub(info.start) >= info.penalty+dropResetAfter
+}
+
+func (conn *Conn) isDropped(id string) bool {
+	conn.lockDrop.RLock()
+	defer conn.lockDrop.RUnlock()
+	info := conn.drop[id]
+	return info != nil && info.dropping(conn.getClock().Now())
+}
+
+// addDrop drop messages of link, the penalty is doubled on repeated drops
+func (conn *Conn) addDrop(id string) {
+	conn.lockDrop.Lock()
+	defer conn.lockDrop.Unlock()
+	info := conn.drop[id]
+	if info == nil {
+		info = new(dropInfo)
+		conn.drop[id] = info
+	}
+	info.strikes++
+	penalty := dropPenalty << uint(info.strikes-1)
+	if penalty > maxDropPenalty || penalty <= 0 {
+		penalty = maxDropPenalty
+	}
+	info.start = conn.getClock().Now()
+	info.penalty = penalty
+	conn.logInfo("link %s dropped for %s, strikes=%d",
+		id, penalty.String(), info.strikes)
+}
+
+func (conn *Conn) checkDrop() {
+	for {
+		clock := conn.getClock()
+		select {
+		case <-clock.After(time.Second):
+		case <-conn.ctx.Done():
+			return
+		}
+
+		now := clock.Now()
+		drops := make([]string, 0, len(conn.drop))
+		conn.lockDrop.RLock()
+		for k, info := range conn.drop {
+			if info.expired(now) {
+				drops = append(drops, k)
+			}
+		}
+		conn.lockDrop.RUnlock()
+
+		conn.lockDrop.Lock()
+		for _, id := range drops {
+			delete(conn.drop, id)
+		}
+		conn.lockDrop.Unlock()
+	}
+}
+
+// DroppedLinks get dropped link ids and their expiry time
+func (conn *Conn) DroppedLinks() map[string]time.Time {
+	conn.lockDrop.RLock()
+	defer conn.lockDrop.RUnlock()
+	now := conn.getClock().Now()
+	ret := make(map[string]time.Time, len(conn.drop))
+	for k, info := range conn.drop {
+		if info.dropping(now) {
+			ret[k] = info.start.Add(info.penalty)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,33 @@
+package conn
+
+import (
+	"github.com/lwch/natpass/code/network"
+	"github.com/lwch/runtime"
+)
+
+// instance random id of this process, it is not changed when reconnecting
+// so that the server can distinguish reconnect from another client using the same id
+var instance, _ = runtime.UUID(16, "0123456789abcdef")
+
+// rejectError get error of rejected handshake
+func rejectError(msg *network.Msg) error {
+	if msg.GetHsp().GetReject() == network.RejectDuplicateID {
+		return ErrDuplicateID
+	}
+	return errHandshakeRejected
+}
+
+// onReject handle rejection received after handshake when handshake_ack is disabled,
+// it is only accepted from server as the first message of the connection
+// because the connection is never reconnected after rejected
+func (conn *Conn) onReject(msg *network.Msg, first bool) {
+	if msg.GetFrom() != "server" || !first {
+		conn.logError("drop unexpected reject from %s", msg.GetFrom())
+		return
+	}
+	err := rejectError(msg)
+	if err == ErrDuplicateID {
+		conn.logError("client id %s is used by another running client, stop reconnecting", conn.cfg.ID)
+	}
+	conn.terminate(err)
+}
@@ -0,0 +1,43 @@
+package conn
+
+import (
+	"testing"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+func rejectMsg(from string) *network.Msg {
+	return &network.Msg{
+		From:  from,
+		To:    "me",
+		XType: network.Msg_reject,
+		Payload: &network.Msg_Hsp{
+			Hsp: &network.HandshakePayload{Reject: network.RejectDuplicateID},
+		},
+	}
+}
+
+func TestRejectFromServer(t *testing.T) {
+	conn := newTestConn(t, nil)
+	conn.handle(rejectMsg("server"))
+	if conn.TerminalError() != ErrDuplicateID {
+		t.Fatalf("want ErrDuplicateID, got %v", conn.TerminalError())
+	}
+}
+
+func TestRejectFromPeerIgnored(t *testing.T) {
+	conn := newTestConn(t, nil)
+	conn.handle(rejectMsg("peer"))
+	if err := conn.TerminalError(); err != nil {
+		t.Fatalf("terminated by peer: %v", err)
+	}
+}
+
+func TestRejectAfterHandshakeIgnored(t *testing.T) {
+	conn := newTestConn(t, nil)
+	conn.handle(&network.Msg{From: "server", XType: network.Msg_keepalive})
+	conn.handle(rejectMsg("server"))
+	if err := conn.TerminalError(); err != nil {
+		t.Fatalf("terminated after handshake: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package conn
+
+import "github.com/lwch/natpass/code/network"
+
+// IsEOF check the message read from link channel means the link is done,
+// a disconnect message is delivered to the link when it is closed by peer,
+// and it is generated locally for all links when the connection is closed or terminated
+func IsEOF(msg *network.Msg) bool {
+	return msg == nil || msg.GetXType() == network.Msg_disconnect
+}
+
+// notifyEOF deliver disconnect message to all links, the oldest buffered message
+// is dropped if the channel is full so that the reader always sees EOF
+func (conn *Conn) notifyEOF() {
+	conn.RLock()
+	defer conn.RUnlock()
+	for id, ch := range conn.read {
+		var msg network.Msg
+		msg.To = conn.cfg.ID
+		msg.XType = network.Msg_disconnect
+		msg.LinkId = id
+		select {
+		case ch <- &msg:
+			continue
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- &msg:
+		default:
+		}
+	}
+}
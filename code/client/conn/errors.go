@@ -0,0 +1,159 @@
+package conn
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+)
+
+var errDropped = errors.New("dropped")
+var errLinkIDConflict = errors.New("link id conflict")
+var errMigrateSelf = errors.New("can not migrate to self")
+var errMigrateCycle = errors.New("target connection is migrated back")
+var errHandshakeNotAcked = errors.New("handshake not acknowledged")
+var errHandshakeRejected = errors.New("handshake rejected")
+
+// ErrDuplicateID the client id is used by another running client
+var ErrDuplicateID = errors.New("duplicate client id")
+
+// ErrTimeout read or write timeout
+var ErrTimeout = errors.New("timeout")
+
+// ErrClosed connection closed
+var ErrClosed = errors.New("connection closed")
+
+// ErrLinkNotFound link not registered
+var ErrLinkNotFound = errors.New("link not found")
+
+// ErrNoLinkCodec payload codec of link type is not registered
+var ErrNoLinkCodec = errors.New("link codec not registered")
+
+// ErrLameduck new link is rejected in lameduck mode
+var ErrLameduck = errors.New("lameduck")
+
+// ErrLinkRateLimited new link exceeded link_rate
+var ErrLinkRateLimited = errors.New("link rate limited")
+
+// ErrWriteFull write queue is full
+var ErrWriteFull = errors.New("write queue full")
+
+// ErrWindowFull messages of link in write queue reached window size
+var ErrWindowFull = errors.New("window full")
+
+// ErrPaused connection is paused with reject policy or the pause queue is full
+var ErrPaused = errors.New("paused")
+
+// ErrBufferFull buffered messages exceeded max_buffer
+var ErrBufferFull = errors.New("buffer full")
+
+// ErrAckTimeout message not acknowledged by server in timeout
+var ErrAckTimeout = errors.New("ack timeout")
+
+// ErrTooManyLinks auto register link reached max_links
+var ErrTooManyLinks = errors.New("too many links")
+
+// ErrDial dial to server failed, includes address resolve errors
+var ErrDial = errors.New("dial")
+
+// ErrTLS tls handshake failed
+var ErrTLS = errors.New("tls handshake")
+
+// ErrHandshake write handshake message failed
+var ErrHandshake = errors.New("handshake")
+
+// ErrWarmup warmup probe after handshake failed
+var ErrWarmup = errors.New("warmup")
+
+// ErrDropped connection closed by peer or network in tls handshake or handshake
+var ErrDropped = errors.New("connection dropped")
+
+// ConnectError error returned by connect, errors.Is matches the stage
+// (ErrDial, ErrTLS, ErrHandshake, ErrWarmup), ErrDropped if the connection
+// was dropped in the middle and the cause
+type ConnectError struct {
+	Stage   error
+	Addr    string
+	Err     error
+	Dropped bool
+}
+
+func newConnectError(stage error, addr string, err error) *ConnectError {
+	return &ConnectError{
+		Stage:   stage,
+		Addr:    addr,
+		Err:     err,
+		Dropped: dropped(err),
+	}
+}
+
+// dropped check the error is caused by connection closed by peer or broken network
+func dropped(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+func (e *ConnectError) Error() string {
+	return e.Stage.Error() + " " + e.Addr + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// Is check the stage of error
+func (e *ConnectError) Is(target error) bool {
+	return e.Stage == target || (e.Dropped && target == ErrDropped)
+}
+
+// ErrMaxAttempts reconnect stopped after max_attempts failures
+var ErrMaxAttempts = errors.New("max reconnect attempts reached")
+
+// AttemptsError error of terminated connection after max_attempts failures,
+// errors.Is matches ErrMaxAttempts and the cause
+type AttemptsError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *AttemptsError) Error() string {
+	return fmt.Sprintf("give up after %d attempts: %v", e.Attempts, e.Err)
+}
+
+// Unwrap returns the cause
+func (e *AttemptsError) Unwrap() error {
+	return e.Err
+}
+
+// Is check target is ErrMaxAttempts
+func (e *AttemptsError) Is(target error) bool {
+	return target == ErrMaxAttempts
+}
+
+// DisconnectReason category of connection broken
+type DisconnectReason string
+
+// ReasonKeepaliveTimeout no message received from server in read deadline
+const ReasonKeepaliveTimeout DisconnectReason = "keepalive_timeout"
+
+// DisconnectError error passed to OnDisconnect callbacks with categorized reason
+type DisconnectError struct {
+	Reason DisconnectReason
+	Quiet  time.Duration // time without any message received
+	Err    error
+}
+
+func (e *DisconnectError) Error() string {
+	return fmt.Sprintf("%s after %s: %v", e.Reason, e.Quiet, e.Err)
+}
+
+// Unwrap returns the cause
+func (e *DisconnectError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,21 @@
+package conn
+
+import "github.com/lwch/natpass/code/network"
+
+// allowed check the message type is allowed on this link
+func (conn *Conn) allowed(id string, t network.MsgType) bool {
+	switch t {
+	case network.Msg_connect_req,
+		network.Msg_connect_rep,
+		network.Msg_disconnect,
+		network.Msg_resend:
+		return true
+	}
+	conn.RLock()
+	allow, ok := conn.allow[id]
+	conn.RUnlock()
+	if !ok {
+		return true
+	}
+	return allow[t]
+}
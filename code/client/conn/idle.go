@@ -0,0 +1,101 @@
+package conn
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/lwch/natpass/code/network"
+	"github.com/lwch/natpass/code/utils"
+)
+
+// active update last active time
+func (conn *Conn) active(msg *network.Msg) {
+	if msg.GetXType() == network.Msg_keepalive {
+		return
+	}
+	atomic.StoreInt64(&conn.lastActive, conn.getClock().Now().UnixNano())
+}
+
+// IsDormant check connection is closed by idle timeout
+func (conn *Conn) IsDormant() bool {
+	conn.lockIdle.Lock()
+	defer conn.lockIdle.Unlock()
+	return conn.dormant
+}
+
+// Wake re-establish the connection closed by idle timeout
+func (conn *Conn) Wake() {
+	var woke bool
+	defer func() {
+		if woke {
+			conn.emitConnect()
+		}
+	}()
+	conn.lockIdle.Lock()
+	defer conn.lockIdle.Unlock()
+	if !conn.dormant {
+		return
+	}
+	conn.logInfo("wake up connection")
+	cn, err := conn.tryConnect()
+	if err != nil {
+		conn.logError("wake up connection: %v", err)
+		return
+	}
+	conn.setConn(cn)
+	woke = true
+	atomic.StoreInt64(&conn.lastActive, conn.getClock().Now().UnixNano())
+	conn.dormant = false
+	close(conn.wake)
+	go conn.restoreLinks()
+}
+
+// waitWake wait for connection wake up if dormant, returns false if not dormant
+func (conn *Conn) waitWake() bool {
+	conn.lockIdle.Lock()
+	if !conn.dormant {
+		conn.lockIdle.Unlock()
+		return false
+	}
+	ch := conn.wake
+	conn.lockIdle.Unlock()
+	<-ch
+	return true
+}
+
+func (conn *Conn) checkIdle() {
+	defer utils.Recover("checkIdle")
+	for {
+		clock := conn.getClock()
+		select {
+		case <-clock.After(time.Second):
+		case <-conn.ctx.Done():
+			return
+		}
+		last := time.Unix(0, atomic.LoadInt64(&conn.lastActive))
+		if clock.Now().Sub(last) < conn.cfg.IdleTimeout {
+			continue
+		}
+		conn.RLock()
+		links := len(conn.read)
+		conn.RUnlock()
+		if links > 0 {
+			continue
+		}
+		var closed *network.Conn
+		conn.lockIdle.Lock()
+		if !conn.dormant {
+			conn.logInfo("connection idle for %s, closed", clock.Now().Sub(last).String())
+			conn.dormant = true
+			conn.wake = make(chan struct{})
+			closed = conn.getConn()
+			atomic.StoreInt32(&conn.connected, 0)
+			closed.Close()
+			conn.closeControl(conn.getControl())
+		}
+		conn.lockIdle.Unlock()
+		if closed != nil {
+			conn.emitDisconnect(closed, nil)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package conn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// nextWritten wait for message queued to any write lane
+func nextWritten(conn *Conn, timeout time.Duration) *network.Msg {
+	deadline := time.After(timeout)
+	for {
+		for _, ch := range conn.write {
+			select {
+			case msg := <-ch:
+				return msg
+			default:
+			}
+		}
+		select {
+		case <-deadline:
+			return nil
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestSetKeepaliveIntervalRearm(t *testing.T) {
+	conn := newTestConn(t, nil)
+	go conn.keepalive()
+	time.Sleep(20 * time.Millisecond)
+	conn.SetKeepaliveInterval(50 * time.Millisecond)
+	msg := nextWritten(conn, time.Second)
+	if msg == nil {
+		t.Fatal("keepalive not sent after interval shortened")
+	}
+	if msg.GetXType() != network.Msg_keepalive {
+		t.Fatalf("unexpected message %s", msg.GetXType().String())
+	}
+}
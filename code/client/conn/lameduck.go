@@ -0,0 +1,39 @@
+package conn
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// EnterLameduck stop accepting new links, wait until existing links removed or maxWait
+// elapsed, then close the connection. AddLink and NewLinkID returns ErrLameduck after called
+func (conn *Conn) EnterLameduck(maxWait time.Duration) {
+	if !atomic.CompareAndSwapInt32(&conn.lameduck, 0, 1) {
+		return
+	}
+	conn.logInfo("enter lameduck, wait %s for %d links", maxWait, conn.linkCount())
+	clk := conn.getClock()
+	deadline := clk.Now().Add(maxWait)
+	for conn.linkCount() > 0 && clk.Now().Before(deadline) {
+		select {
+		case <-clk.After(100 * time.Millisecond):
+		case <-conn.ctx.Done():
+			return
+		}
+	}
+	if n := conn.linkCount(); n > 0 {
+		conn.logInfo("lameduck timeout, close with %d links", n)
+	}
+	conn.Close()
+}
+
+// IsLameduck check connection is in lameduck mode
+func (conn *Conn) IsLameduck() bool {
+	return atomic.LoadInt32(&conn.lameduck) == 1
+}
+
+func (conn *Conn) linkCount() int {
+	conn.RLock()
+	defer conn.RUnlock()
+	return len(conn.read)
+}
@@ -0,0 +1,20 @@
+package conn
+
+import "github.com/lwch/natpass/code/network"
+
+// EncodeLatency get histogram of message encode latency
+func (conn *Conn) EncodeLatency() network.HistogramSnapshot {
+	return conn.encode.Snapshot()
+}
+
+// DecodeLatency get histogram of message decode latency
+func (conn *Conn) DecodeLatency() network.HistogramSnapshot {
+	return conn.decode.Snapshot()
+}
+
+// CompressionStats get cumulative bytes of compressed messages before and after compression.
+// only the handshake is compressed by compress_handshake, data messages are never compressed,
+// so it reflects the handshakes of all connections rather than the traffic
+func (conn *Conn) CompressionStats() (inRaw, inComp, outRaw, outComp uint64) {
+	return conn.compressStats.Load()
+}
@@ -0,0 +1,33 @@
+package conn
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lwch/natpass/code/client/global"
+	"github.com/lwch/natpass/code/network"
+)
+
+func TestCompressionStatsOfHandshake(t *testing.T) {
+	conn := newTestConn(t, func(cfg *global.Configure) {
+		cfg.HandshakeExt = map[string]string{"tags": strings.Repeat("natpass", 100)}
+	})
+	a, b := net.Pipe()
+	r := network.NewConn(b)
+	defer r.Close()
+	cn := network.NewConn(a)
+	defer cn.Close()
+	cn.SetCompressStats(conn.compressStats)
+	if err := writeHandshake(cn, conn.cfg, 0, false, true, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// wait for the handshake written so that nothing fails on close
+	if _, _, err := r.ReadMessage(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, outRaw, outComp := conn.CompressionStats(); outRaw == 0 || outComp >= outRaw {
+		t.Fatalf("handshake not counted: raw=%d, comp=%d", outRaw, outComp)
+	}
+}
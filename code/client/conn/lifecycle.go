@@ -0,0 +1,94 @@
+package conn
+
+import (
+	"time"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// HandshakeResult result of completed handshake
+type HandshakeResult struct {
+	Server  string
+	Codec   string
+	Control bool // handshake of control connection
+	TLS     bool
+	Elapsed time.Duration // dial and handshake time
+}
+
+// lifecycle callbacks of connection
+type lifecycle struct {
+	onConnect    []func()
+	onDisconnect []func(error)
+	onHandshake  []func(HandshakeResult)
+	handshakes   []HandshakeResult // not emitted handshake results
+	down         *network.Conn     // last disconnected connection
+}
+
+// OnConnect add callback when connection is established, include reconnected and waked up
+func (conn *Conn) OnConnect(fn func()) {
+	conn.Lock()
+	conn.hooks.onConnect = append(conn.hooks.onConnect, fn)
+	conn.Unlock()
+}
+
+// OnDisconnect add callback when connection is broken, the error is nil when it is
+// closed by Close, Reconnect or idle timeout, use errors.As with *DisconnectError to get the reason
+func (conn *Conn) OnDisconnect(fn func(error)) {
+	conn.Lock()
+	conn.hooks.onDisconnect = append(conn.hooks.onDisconnect, fn)
+	conn.Unlock()
+}
+
+// OnHandshakeComplete add callback when handshake is completed, called before OnConnect callbacks
+func (conn *Conn) OnHandshakeComplete(fn func(HandshakeResult)) {
+	conn.Lock()
+	conn.hooks.onHandshake = append(conn.hooks.onHandshake, fn)
+	conn.Unlock()
+}
+
+// addHandshake save handshake result, it is emitted with connect event
+// because handshake may be done with internal locks held
+func (conn *Conn) addHandshake(result HandshakeResult) {
+	conn.Lock()
+	conn.hooks.handshakes = append(conn.hooks.handshakes, result)
+	conn.Unlock()
+}
+
+// emitConnect call handshake and connect callbacks, must be called without internal locks
+func (conn *Conn) emitConnect() {
+	conn.Lock()
+	handshakes := conn.hooks.handshakes
+	conn.hooks.handshakes = nil
+	onHandshake := conn.hooks.onHandshake
+	onConnect := conn.hooks.onConnect
+	conn.Unlock()
+	conn.rttHistory.add(RTTSample{
+		Time:      time.Now(),
+		Server:    conn.CurrentServer(),
+		Reconnect: true,
+	})
+	for _, result := range handshakes {
+		for _, fn := range onHandshake {
+			fn(result)
+		}
+	}
+	for _, fn := range onConnect {
+		fn()
+	}
+}
+
+// emitDisconnect call disconnect callbacks once for each connection,
+// must be called without internal locks
+func (conn *Conn) emitDisconnect(cn *network.Conn, err error) {
+	conn.Lock()
+	if conn.hooks.down == cn {
+		conn.Unlock()
+		return
+	}
+	conn.hooks.down = cn
+	fns := conn.hooks.onDisconnect
+	conn.Unlock()
+	for _, fn := range fns {
+		fn(err)
+	}
+}
@@ -0,0 +1,75 @@
+package conn
+
+import "github.com/lwch/natpass/code/network"
+
+// Codec payload encoder and decoder of link type, link handlers send and read
+// typed values instead of raw bytes
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// RegisterLinkCodec register payload codec of link type, nil to remove
+func (conn *Conn) RegisterLinkCodec(linkType string, codec Codec) {
+	conn.Lock()
+	defer conn.Unlock()
+	if codec == nil {
+		delete(conn.codecs, linkType)
+		return
+	}
+	conn.codecs[linkType] = codec
+}
+
+// SetLinkType set type of registered link, the payload is encoded by codec of the type
+func (conn *Conn) SetLinkType(id, linkType string) {
+	conn.Lock()
+	defer conn.Unlock()
+	opt, ok := conn.registry[id]
+	if !ok {
+		return
+	}
+	opt.linkType = linkType
+	conn.registry[id] = opt
+}
+
+// linkCodec get payload codec of link, returns ErrNoLinkCodec if not registered
+func (conn *Conn) linkCodec(id string) (Codec, error) {
+	conn.RLock()
+	defer conn.RUnlock()
+	codec := conn.codecs[conn.registry[id].linkType]
+	if codec == nil {
+		return nil, ErrNoLinkCodec
+	}
+	return codec, nil
+}
+
+// SendTyped encode v by codec of link and send it as forward message
+func (conn *Conn) SendTyped(to, id string, v interface{}) error {
+	codec, err := conn.linkCodec(id)
+	if err != nil {
+		return err
+	}
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var msg network.Msg
+	msg.To = to
+	msg.XType = network.Msg_forward
+	msg.LinkId = id
+	msg.Payload = &network.Msg_XData{
+		XData: &network.Data{
+			Data: data,
+		},
+	}
+	return conn.Send(&msg)
+}
+
+// DecodeTyped decode payload of message sent by SendTyped into v by codec of link
+func (conn *Conn) DecodeTyped(msg *network.Msg, v interface{}) error {
+	codec, err := conn.linkCodec(msg.GetLinkId())
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(msg.GetXData().GetData(), v)
+}
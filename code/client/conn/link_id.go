@@ -0,0 +1,28 @@
+package conn
+
+import "github.com/lwch/runtime"
+
+// NewLinkID generate a new link id which is not registered on this connection,
+// the id is prefixed by session id of current connection so that late messages of
+// links in previous sessions never reach a new link, returns ErrLameduck in lameduck mode
+func (conn *Conn) NewLinkID() (string, error) {
+	if conn.IsLameduck() {
+		return "", ErrLameduck
+	}
+	for i := 0; i < 10; i++ {
+		id, err := runtime.UUID(16, "0123456789abcdef")
+		if err != nil {
+			return "", err
+		}
+		if session := conn.Session(); len(session) > 0 {
+			id = session + "-" + id
+		}
+		conn.RLock()
+		_, ok := conn.read[id]
+		conn.RUnlock()
+		if !ok {
+			return id, nil
+		}
+	}
+	return "", errLinkIDConflict
+}
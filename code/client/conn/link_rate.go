@@ -0,0 +1,79 @@
+package conn
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// linkLimiter token bucket of link creation
+type linkLimiter struct {
+	sync.Mutex
+	rate    float64 // tokens per second, 0 means unlimited
+	burst   float64
+	tokens  float64
+	last    time.Time
+	start   time.Time // start of current counting window
+	count   int       // links created in current window
+	current float64   // links created per second in last window
+}
+
+func newLinkLimiter(rate float64, burst int) *linkLimiter {
+	return &linkLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// allow take a token, returns false if the bucket is empty
+func (l *linkLimiter) allow(now time.Time) bool {
+	l.Lock()
+	defer l.Unlock()
+	l.roll(now)
+	if l.rate > 0 {
+		if !l.last.IsZero() {
+			l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		}
+		l.last = now
+		if l.tokens < 1 {
+			return false
+		}
+		l.tokens--
+	}
+	l.count++
+	return true
+}
+
+// roll start a new counting window every second
+func (l *linkLimiter) roll(now time.Time) {
+	elapsed := now.Sub(l.start)
+	if elapsed < time.Second {
+		return
+	}
+	if elapsed < 2*time.Second {
+		l.current = float64(l.count) / elapsed.Seconds()
+	} else {
+		l.current = 0
+	}
+	l.start = now
+	l.count = 0
+}
+
+// allowLink check link creation rate limit of link_rate and link_burst
+func (conn *Conn) allowLink(id string) error {
+	if conn.linkLimit.allow(conn.getClock().Now()) {
+		return nil
+	}
+	conn.logError("add link %s rate limited", id)
+	return ErrLinkRateLimited
+}
+
+// LinkRate get links created per second in the last second
+func (conn *Conn) LinkRate() float64 {
+	l := conn.linkLimit
+	l.Lock()
+	defer l.Unlock()
+	l.roll(conn.getClock().Now())
+	return l.current
+}
@@ -0,0 +1,43 @@
+package conn
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// LastReadAt get time of last message read from data or control connection,
+// keepalives are included, zero time if nothing read yet
+func (conn *Conn) LastReadAt() time.Time {
+	return unixNano(atomic.LoadInt64(&conn.readAt))
+}
+
+// LastWriteAt get time of last message written to data or control connection,
+// keepalives are included, zero time if nothing written yet
+func (conn *Conn) LastWriteAt() time.Time {
+	return unixNano(atomic.LoadInt64(&conn.writeAt))
+}
+
+// IsConnected check the connection is usable now: established, not closed and
+// any message received in read deadline, it is cheap to be called frequently
+func (conn *Conn) IsConnected() bool {
+	if atomic.LoadInt32(&conn.connected) == 0 || conn.ctx.Err() != nil {
+		return false
+	}
+	last := time.Unix(0, atomic.LoadInt64(&conn.lastRead))
+	return time.Since(last) < conn.ReadDeadline()
+}
+
+func (conn *Conn) markRead() {
+	atomic.StoreInt64(&conn.readAt, conn.getClock().Now().UnixNano())
+}
+
+func (conn *Conn) markWrite() {
+	atomic.StoreInt64(&conn.writeAt, conn.getClock().Now().UnixNano())
+}
+
+func unixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
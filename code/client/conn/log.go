@@ -0,0 +1,36 @@
+package conn
+
+import "github.com/lwch/logging"
+
+// logPrefix prefix of logs, name of connection is added to disambiguate
+// logs of multiple connections in one process
+func (conn *Conn) logPrefix(format string) string {
+	if len(conn.cfg.Name) == 0 {
+		return format
+	}
+	return "[" + conn.cfg.Name + "] " + format
+}
+
+func (conn *Conn) logInfo(format string, args ...interface{}) {
+	logging.Info(conn.logPrefix(format), args...)
+}
+
+func (conn *Conn) logError(format string, args ...interface{}) {
+	logging.Error(conn.logPrefix(format), args...)
+}
+
+func (conn *Conn) logDebug(format string, args ...interface{}) {
+	logging.Debug(conn.logPrefix(format), args...)
+}
+
+// Labels get labels of connection for metrics, includes name if set
+func (conn *Conn) Labels() map[string]string {
+	ret := make(map[string]string, len(conn.cfg.Labels)+1)
+	for k, v := range conn.cfg.Labels {
+		ret[k] = v
+	}
+	if len(conn.cfg.Name) > 0 {
+		ret["name"] = conn.cfg.Name
+	}
+	return ret
+}
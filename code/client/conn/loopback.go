@@ -0,0 +1,21 @@
+package conn
+
+import (
+	"github.com/lwch/natpass/code/network"
+	"google.golang.org/protobuf/proto"
+)
+
+// loopback deliver message sent to the client itself without the round trip of server
+// when loopback is enabled, the message is routed like received ones so it is dropped
+// if the link is not registered, returns false if the message should be written to server
+func (conn *Conn) loopback(msg *network.Msg) bool {
+	if !conn.cfg.Loopback || msg.GetTo() != conn.cfg.ID {
+		return false
+	}
+	if msg.GetReqId() > 0 {
+		conn.onAck(msg.GetReqId())
+	}
+	// copied like written to the wire, the sender may keep the message
+	conn.dispatch(proto.Clone(msg).(*network.Msg))
+	return true
+}
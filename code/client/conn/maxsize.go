@@ -0,0 +1,8 @@
+package conn
+
+import "sync/atomic"
+
+// MaxMessageSize get max message size negotiated with server in handshake
+func (conn *Conn) MaxMessageSize() uint32 {
+	return atomic.LoadUint32(&conn.maxSize)
+}
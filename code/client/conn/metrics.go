@@ -0,0 +1,47 @@
+package conn
+
+import (
+	"time"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// metricsReport state of metrics piggybacked on keepalive, only used by keepalive goroutine
+type metricsReport struct {
+	at     time.Time
+	msgIn  uint64
+	msgOut uint64
+	rtt    time.Duration // rtt of last keepalive
+}
+
+// attachMetrics attach metrics to keepalive message when keepalive_metrics interval elapsed,
+// counters are deltas since last report to keep the message small, the first call only records the baseline
+func (conn *Conn) attachMetrics(msg *network.Msg, now time.Time) {
+	if conn.cfg.KeepaliveMetrics <= 0 {
+		return
+	}
+	r := &conn.report
+	if !r.at.IsZero() && now.Sub(r.at) < conn.cfg.KeepaliveMetrics {
+		return
+	}
+	in, out := conn.MessageTypeCounts()
+	var msgIn, msgOut uint64
+	for _, n := range in {
+		msgIn += n
+	}
+	for _, n := range out {
+		msgOut += n
+	}
+	if !r.at.IsZero() {
+		msg.Metrics = &network.KeepaliveMetrics{
+			Links:    uint32(conn.linkCount()),
+			MsgIn:    msgIn - r.msgIn,
+			MsgOut:   msgOut - r.msgOut,
+			Rtt:      uint32(r.rtt.Milliseconds()),
+			Interval: uint32(now.Sub(r.at).Seconds()),
+		}
+	}
+	r.at = now
+	r.msgIn = msgIn
+	r.msgOut = msgOut
+}
@@ -0,0 +1,218 @@
+package conn
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// lockMigrate serialize migrations so that the migrated chain is not changed while checking
+var lockMigrate sync.Mutex
+
+// MigrateLinksTo move all links to other connection, the read channels are
+// kept so the link consumers are not affected. the per-link state of dedup,
+// resend, flow control window, coalescing, tee and drop penalty is moved with
+// the links, and the peer of each link is notified by migrate message on this
+// connection so that it can re-route, see OnPeerMigrate. After migration
+// messages sent on this connection are forwarded to other connection, so
+// migrating to a connection which is migrated back to this one is refused.
+func (conn *Conn) MigrateLinksTo(other *Conn) error {
+	if other == nil || other == conn {
+		return errMigrateSelf
+	}
+	lockMigrate.Lock()
+	defer lockMigrate.Unlock()
+	// messages sent are forwarded along the migrated connections
+	for c := other; c != nil; c = c.getMigrated() {
+		if c == conn {
+			return errMigrateCycle
+		}
+	}
+	ids, err := conn.migrateLinks(other)
+	if err != nil {
+		return err
+	}
+	conn.migrateState(other, ids)
+	server := other.CurrentServer()
+	for _, id := range ids {
+		conn.notifyMigrate(id, other.peer(id), server)
+		conn.logInfo("link %s migrated to %s", id, server)
+	}
+	return nil
+}
+
+// lockBoth lock the mutex of both connections in creation order,
+// so that migrations in opposite directions do not deadlock
+func lockBoth(a, b *Conn, mu func(*Conn) sync.Locker) func() {
+	if a.order > b.order {
+		a, b = b, a
+	}
+	mu(a).Lock()
+	mu(b).Lock()
+	return func() {
+		mu(b).Unlock()
+		mu(a).Unlock()
+	}
+}
+
+// migrateLinks move read channels and registrations of links, returns the moved link ids
+func (conn *Conn) migrateLinks(other *Conn) ([]string, error) {
+	unlock := lockBoth(conn, other, func(c *Conn) sync.Locker { return c })
+	defer unlock()
+	for id := range conn.read {
+		if _, ok := other.read[id]; ok {
+			return nil, fmt.Errorf("link %s already exists on target connection", id)
+		}
+	}
+	ids := make([]string, 0, len(conn.read))
+	for id, ch := range conn.read {
+		other.read[id] = ch
+		if allow, ok := conn.allow[id]; ok {
+			other.allow[id] = allow
+		}
+		if opt, ok := conn.registry[id]; ok {
+			other.registry[id] = opt
+		}
+		ids = append(ids, id)
+	}
+	conn.read = make(map[string]chan *network.Msg)
+	conn.allow = make(map[string]map[network.MsgType]bool)
+	conn.registry = make(map[string]linkOptions)
+	conn.migrated = other
+	return ids, nil
+}
+
+// migrateState move per-link state of links, messages already queued on this
+// connection are written by it so the inflight count of window is not moved
+func (conn *Conn) migrateState(other *Conn, ids []string) {
+	unlock := lockBoth(conn, other, func(c *Conn) sync.Locker { return &c.lockDedup })
+	for _, id := range ids {
+		if info, ok := conn.dedup[id]; ok {
+			other.dedup[id] = info
+			delete(conn.dedup, id)
+		}
+	}
+	unlock()
+
+	unlock = lockBoth(conn, other, func(c *Conn) sync.Locker { return &c.lockResend })
+	for _, id := range ids {
+		if buf, ok := conn.resend[id]; ok {
+			other.resend[id] = buf
+			delete(conn.resend, id)
+		}
+	}
+	unlock()
+
+	unlock = lockBoth(conn, other, func(c *Conn) sync.Locker { return &c.lockTee })
+	for _, id := range ids {
+		if t, ok := conn.tees[id]; ok {
+			other.tees[id] = t
+			delete(conn.tees, id)
+		}
+	}
+	unlock()
+
+	unlock = lockBoth(conn, other, func(c *Conn) sync.Locker { return &c.lockDrop })
+	for _, id := range ids {
+		if info, ok := conn.drop[id]; ok {
+			other.drop[id] = info
+			delete(conn.drop, id)
+		}
+	}
+	unlock()
+
+	unlock = lockBoth(conn, other, func(c *Conn) sync.Locker { return &c.lockPeers })
+	for _, id := range ids {
+		if peer, ok := conn.peers[id]; ok {
+			other.peers[id] = peer
+			delete(conn.peers, id)
+		}
+	}
+	unlock()
+
+	for _, id := range ids {
+		conn.lockWindow.Lock()
+		w := conn.windows[id]
+		var size int
+		if w != nil {
+			size = w.size
+		}
+		conn.lockWindow.Unlock()
+		if size > 0 {
+			conn.SetLinkWindow(id, 0)
+			other.SetLinkWindow(id, size)
+		}
+
+		conn.lockCoalesce.Lock()
+		c := conn.coalesce[id]
+		conn.lockCoalesce.Unlock()
+		if c != nil {
+			// buffered data is flushed to other connection
+			conn.SetLinkCoalesce(id, 0, 0)
+			other.SetLinkCoalesce(id, c.window, c.max)
+		}
+	}
+}
+
+// notifyMigrate send migrate message to peer of link on this connection,
+// the peer is still reachable from the server of this connection
+func (conn *Conn) notifyMigrate(id, peer, server string) {
+	if len(peer) == 0 {
+		return
+	}
+	var msg network.Msg
+	msg.To = peer
+	msg.XType = network.Msg_migrate
+	msg.LinkId = id
+	msg.Payload = &network.Msg_Mnotify{
+		Mnotify: &network.MigrateNotify{
+			Server: server,
+		},
+	}
+	// not Send which forwards to the migrated connection
+	if err := conn.send(&msg); err != nil {
+		conn.logError("notify migration of link %s to %s: %v", id, peer, err)
+	}
+}
+
+// OnPeerMigrate set callback of link migrated by peer, server is the address
+// of the connection the peer migrated to, nil to disable
+func (conn *Conn) OnPeerMigrate(fn func(id, peer, server string)) {
+	conn.Lock()
+	conn.onPeerMigrate = fn
+	conn.Unlock()
+}
+
+func (conn *Conn) onMigrate(msg *network.Msg) {
+	server := msg.GetMnotify().GetServer()
+	conn.logInfo("link %s migrated by %s to %s", msg.GetLinkId(), msg.GetFrom(), server)
+	conn.RLock()
+	fn := conn.onPeerMigrate
+	conn.RUnlock()
+	if fn != nil {
+		fn(msg.GetLinkId(), msg.GetFrom(), server)
+	}
+}
+
+// setPeer record peer of link by received message
+func (conn *Conn) setPeer(id, peer string) {
+	conn.lockPeers.Lock()
+	if conn.peers[id] != peer {
+		conn.peers[id] = peer
+	}
+	conn.lockPeers.Unlock()
+}
+
+// peer get peer of link, empty if no message received
+func (conn *Conn) peer(id string) string {
+	conn.lockPeers.Lock()
+	defer conn.lockPeers.Unlock()
+	return conn.peers[id]
+}
+
+func (conn *Conn) getMigrated() *Conn {
+	conn.RLock()
+	defer conn.RUnlock()
+	return conn.migrated
+}
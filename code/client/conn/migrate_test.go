@@ -0,0 +1,108 @@
+package conn
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+func TestMigrateLinksState(t *testing.T) {
+	a := newTestConn(t, nil)
+	b := newTestConn(t, nil)
+	if err := a.AddLink("l1"); err != nil {
+		t.Fatal(err)
+	}
+	a.SetLinkWindow("l1", 8)
+	a.EnableDedup("l1")
+	a.handle(&network.Msg{From: "peer", XType: network.Msg_forward, LinkId: "l1", Seq: 1})
+	<-a.ChanRead("l1")
+	if err := a.MigrateLinksTo(b); err != nil {
+		t.Fatal(err)
+	}
+	if b.ChanRead("l1") == nil || a.ChanRead("l1") != nil {
+		t.Fatal("read channel not migrated")
+	}
+	if b.LinkInflight("l1") != 0 || a.LinkInflight("l1") != -1 {
+		t.Fatal("window not migrated")
+	}
+	if !b.isDuplicate("l1", 1) {
+		t.Fatal("dedup window not migrated")
+	}
+	if b.peer("l1") != "peer" {
+		t.Fatal("peer not migrated")
+	}
+	msg := nextWritten(a, time.Second)
+	if msg == nil || msg.GetXType() != network.Msg_migrate || msg.GetTo() != "peer" {
+		t.Fatalf("peer not notified: %v", msg)
+	}
+}
+
+func TestPeerMigrateCallback(t *testing.T) {
+	conn := newTestConn(t, nil)
+	var got string
+	conn.OnPeerMigrate(func(id, peer, server string) {
+		got = fmt.Sprintf("%s/%s/%s", id, peer, server)
+	})
+	conn.handle(&network.Msg{From: "peer", XType: network.Msg_migrate, LinkId: "l1",
+		Payload: &network.Msg_Mnotify{Mnotify: &network.MigrateNotify{Server: "10.0.0.1:6154"}}})
+	if got != "l1/peer/10.0.0.1:6154" {
+		t.Fatalf("unexpected callback: %s", got)
+	}
+}
+
+func TestMigrateOppositeDirections(t *testing.T) {
+	a := newTestConn(t, nil)
+	b := newTestConn(t, nil)
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				a.MigrateLinksTo(b)
+			}()
+			go func() {
+				defer wg.Done()
+				b.MigrateLinksTo(a)
+			}()
+		}
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock in migrations of opposite directions")
+	}
+}
+
+func TestMigrateCycle(t *testing.T) {
+	a := newTestConn(t, nil)
+	b := newTestConn(t, nil)
+	c := newTestConn(t, nil)
+	if err := a.MigrateLinksTo(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MigrateLinksTo(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.MigrateLinksTo(a); err != errMigrateCycle {
+		t.Fatalf("want errMigrateCycle, got %v", err)
+	}
+	if err := c.MigrateLinksTo(a); err != errMigrateCycle {
+		t.Fatalf("want errMigrateCycle, got %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Send(&network.Msg{To: "peer", XType: network.Msg_forward, LinkId: "l1"})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("send not returned on migrated connections")
+	}
+}
@@ -0,0 +1,81 @@
+package conn
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lwch/logging"
+	"github.com/lwch/natpass/code/client/global"
+	"github.com/lwch/natpass/code/network"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	msgLogRecv = "recv"
+	msgLogSend = "send"
+)
+
+// MessageRecord record of message log
+type MessageRecord struct {
+	Time    time.Time `json:"time"`
+	Dir     string    `json:"dir"`
+	Type    string    `json:"type"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	LinkID  string    `json:"link_id,omitempty"`
+	Size    int       `json:"size"`
+	Payload []byte    `json:"payload,omitempty"` // marshaled message
+}
+
+type msgLogger struct {
+	logger  logging.Logger
+	payload bool
+}
+
+func newMsgLogger(cfg *global.Configure) *msgLogger {
+	if !cfg.MessageLog {
+		return nil
+	}
+	return &msgLogger{
+		logger: logging.NewRotateSizeLogger(logging.SizeRotateConfig{
+			Dir:       cfg.LogDir,
+			Name:      "np-cli.msg",
+			Size:      int64(cfg.MessageLogSize.Bytes()),
+			Rotate:    cfg.MessageLogRotate,
+			WriteFile: true,
+		}),
+		payload: cfg.MessageLogPayload,
+	}
+}
+
+func (l *msgLogger) log(dir string, msg *network.Msg) {
+	if l == nil {
+		return
+	}
+	line, err := messageRecord(dir, msg, l.payload)
+	if err != nil {
+		return
+	}
+	l.logger.Write(line)
+}
+
+// messageRecord encode message to json record line
+func messageRecord(dir string, msg *network.Msg, payload bool) ([]byte, error) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	record := MessageRecord{
+		Time:   time.Now(),
+		Dir:    dir,
+		Type:   msg.GetXType().String(),
+		From:   msg.GetFrom(),
+		To:     msg.GetTo(),
+		LinkID: msg.GetLinkId(),
+		Size:   len(data),
+	}
+	if payload {
+		record.Payload = data
+	}
+	return json.Marshal(record)
+}
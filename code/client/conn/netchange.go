@@ -0,0 +1,65 @@
+package conn
+
+import (
+	"net"
+	"time"
+
+	"github.com/lwch/natpass/code/utils"
+)
+
+// checkNetwork reconnect when the local address of current connection
+// is removed from network interfaces, e.g. switching between wifi and cellular
+func (conn *Conn) checkNetwork() {
+	defer utils.Recover("checkNetwork")
+	changed := make(chan struct{}, 1)
+	go func() {
+		err := watchNetwork(conn.ctx, func() {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+		if err != nil {
+			conn.logError("watch network change: %v", err)
+		}
+	}()
+	for {
+		select {
+		case <-changed:
+		case <-conn.ctx.Done():
+			return
+		}
+		// wait for address configured
+		time.Sleep(time.Second)
+		select {
+		case <-changed:
+		default:
+		}
+		if conn.IsDormant() || localAddrExists(conn.getConn().LocalAddr()) {
+			continue
+		}
+		conn.logInfo("network changed, reconnect")
+		err := conn.Reconnect()
+		if err != nil {
+			conn.logError("reconnect on network changed: %v", err)
+		}
+	}
+}
+
+// localAddrExists check ip address of addr is still on local network interfaces
+func localAddrExists(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return true
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.Equal(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package conn
+
+import (
+	"context"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchNetwork call fn when link or address of local network interface changed
+func watchNetwork(ctx context.Context, fn func()) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return err
+	}
+	err = unix.Bind(fd, &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR,
+	})
+	if err != nil {
+		unix.Close(fd)
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		unix.Shutdown(fd, unix.SHUT_RDWR)
+		unix.Close(fd)
+	}()
+	buf := make([]byte, 4096)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == unix.EINTR || err == unix.ENOBUFS {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			fn()
+		}
+	}
+}
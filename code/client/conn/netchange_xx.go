@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package conn
+
+import "context"
+
+// watchNetwork network change is not supported, reconnect is triggered by read or write error
+func watchNetwork(ctx context.Context, fn func()) error {
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,51 @@
+package conn
+
+import "sync"
+
+// linkOrder ticket lock of link, concurrent senders of the same link
+// queue messages in the order of taking tickets
+type linkOrder struct {
+	sync.Mutex
+	cond    *sync.Cond
+	next    uint64 // next ticket, protected by lockOrder
+	users   int    // senders holding or waiting tickets, protected by lockOrder
+	serving uint64
+}
+
+// orderLink wait for turn of link in send order, returns the function to pass the turn,
+// messages without link are not ordered
+func (conn *Conn) orderLink(id string) func() {
+	if len(id) == 0 {
+		return func() {}
+	}
+	conn.lockOrder.Lock()
+	o := conn.orders[id]
+	if o == nil {
+		o = &linkOrder{}
+		o.cond = sync.NewCond(&o.Mutex)
+		conn.orders[id] = o
+	}
+	ticket := o.next
+	o.next++
+	o.users++
+	conn.lockOrder.Unlock()
+
+	o.Lock()
+	for o.serving != ticket {
+		o.cond.Wait()
+	}
+	o.Unlock()
+
+	return func() {
+		o.Lock()
+		o.serving++
+		o.cond.Broadcast()
+		o.Unlock()
+		conn.lockOrder.Lock()
+		o.users--
+		if o.users == 0 {
+			delete(conn.orders, id)
+		}
+		conn.lockOrder.Unlock()
+	}
+}
@@ -0,0 +1,121 @@
+package conn
+
+import (
+	"sync/atomic"
+
+	"github.com/lwch/natpass/code/client/global"
+	"github.com/lwch/natpass/code/network"
+)
+
+// pauseQueueSize max messages queued in each direction while paused
+const pauseQueueSize = 4096
+
+const (
+	pauseNone     int32 = iota
+	pausePaused         // application messages are held
+	pauseResuming       // held messages are processing by Resume
+)
+
+type heldMsg struct {
+	linkID string
+	msg    *network.Msg
+}
+
+// Pause stop sending and delivering application messages while the connection and
+// keepalive are kept, control messages like connect and disconnect are not affected.
+// with pause policy queue the messages are queued and processed by Resume,
+// with pause policy reject Send returns ErrPaused and received messages are dropped
+func (conn *Conn) Pause() {
+	conn.lockPause.Lock()
+	defer conn.lockPause.Unlock()
+	if atomic.LoadInt32(&conn.pause) == pausePaused {
+		return
+	}
+	atomic.StoreInt32(&conn.pause, pausePaused)
+	conn.logInfo("connection paused")
+}
+
+// Resume process the queued messages in order and continue sending and delivering,
+// Pause called while resuming stops processing the rest of queued messages
+func (conn *Conn) Resume() {
+	conn.lockPause.Lock()
+	if atomic.LoadInt32(&conn.pause) != pausePaused {
+		conn.lockPause.Unlock()
+		return
+	}
+	atomic.StoreInt32(&conn.pause, pauseResuming)
+	for {
+		if atomic.LoadInt32(&conn.pause) != pauseResuming {
+			conn.lockPause.Unlock()
+			return
+		}
+		send, read := conn.heldSend, conn.heldRead
+		conn.heldSend, conn.heldRead = nil, nil
+		if len(send) == 0 && len(read) == 0 {
+			atomic.StoreInt32(&conn.pause, pauseNone)
+			conn.lockPause.Unlock()
+			break
+		}
+		conn.lockPause.Unlock()
+		for _, h := range send {
+			if err := conn.send(h.msg); err != nil {
+				conn.logError("send queued message %s on link %s: %v",
+					h.msg.GetXType().String(), h.linkID, err)
+			}
+		}
+		for _, h := range read {
+			conn.deliver(h.linkID, h.msg)
+		}
+		conn.lockPause.Lock()
+	}
+	conn.logInfo("connection resumed")
+}
+
+// IsPaused check connection is paused or processing queued messages of Resume
+func (conn *Conn) IsPaused() bool {
+	return atomic.LoadInt32(&conn.pause) != pauseNone
+}
+
+// holdSend queue or reject application message to send while paused, returns false if not held
+func (conn *Conn) holdSend(msg *network.Msg) (bool, error) {
+	if atomic.LoadInt32(&conn.pause) == pauseNone || isControl(msg) {
+		return false, nil
+	}
+	conn.lockPause.Lock()
+	defer conn.lockPause.Unlock()
+	if atomic.LoadInt32(&conn.pause) == pauseNone {
+		return false, nil
+	}
+	if conn.cfg.PausePolicy == global.PauseReject ||
+		len(conn.heldSend) >= pauseQueueSize {
+		return true, ErrPaused
+	}
+	conn.heldSend = append(conn.heldSend, heldMsg{linkID: msg.GetLinkId(), msg: msg})
+	return true, nil
+}
+
+// holdRead queue or drop received application message while paused, returns false if not held
+func (conn *Conn) holdRead(linkID string, msg *network.Msg) bool {
+	if atomic.LoadInt32(&conn.pause) == pauseNone || isControl(msg) {
+		return false
+	}
+	conn.lockPause.Lock()
+	defer conn.lockPause.Unlock()
+	if atomic.LoadInt32(&conn.pause) == pauseNone {
+		return false
+	}
+	if conn.cfg.PausePolicy == global.PauseReject {
+		conn.logDebug("drop message %s on link %s while paused",
+			msg.GetXType().String(), linkID)
+		conn.Release(msg)
+		return true
+	}
+	if len(conn.heldRead) >= pauseQueueSize {
+		conn.logError("pause queue full, drop message %s on link %s",
+			msg.GetXType().String(), linkID)
+		conn.Release(msg)
+		return true
+	}
+	conn.heldRead = append(conn.heldRead, heldMsg{linkID: linkID, msg: msg})
+	return true
+}
@@ -0,0 +1,31 @@
+package conn
+
+import (
+	"testing"
+
+	"github.com/lwch/natpass/code/client/global"
+	"github.com/lwch/natpass/code/network"
+)
+
+func TestPauseDropReleased(t *testing.T) {
+	for _, policy := range []string{global.PauseReject, global.PauseQueue} {
+		conn := newTestConn(t, func(cfg *global.Configure) {
+			cfg.ReadPool = true
+			cfg.PausePolicy = policy
+		})
+		conn.Pause()
+		if policy == global.PauseQueue {
+			for i := 0; i < pauseQueueSize; i++ {
+				conn.holdRead("l1", &network.Msg{XType: network.Msg_forward})
+			}
+		}
+		msg := &network.Msg{XType: network.Msg_forward, LinkId: "l1"}
+		if !conn.holdRead("l1", msg) {
+			t.Fatalf("%s: message not held while paused", policy)
+		}
+		// released message is reset
+		if msg.GetXType() != network.Msg_unknown {
+			t.Fatalf("%s: dropped message not released", policy)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package conn
+
+import (
+	"sync/atomic"
+
+	"github.com/lwch/natpass/code/network"
+	"google.golang.org/protobuf/proto"
+)
+
+// PendingWriteBytes get serialized size of messages in write queue
+func (conn *Conn) PendingWriteBytes() int {
+	n := atomic.LoadInt64(&conn.pendingSize)
+	if n < 0 {
+		return 0
+	}
+	return int(n)
+}
+
+// PendingWriteCount get count of messages in write queue
+func (conn *Conn) PendingWriteCount() int {
+	var n int
+	for _, ch := range conn.write {
+		n += len(ch)
+	}
+	return n
+}
+
+// addPending add size of message to pending bytes and count it in window of link,
+// sign is 1 for enqueue and -1 for dequeue, returns the size added
+func (conn *Conn) addPending(msg *network.Msg, sign int64) int64 {
+	size := sign * int64(proto.Size(msg))
+	conn.pendingBytes(size)
+	conn.moveWindow(msg.GetLinkId(), int(sign))
+	return size
+}
+
+func (conn *Conn) pendingBytes(n int64) {
+	atomic.AddInt64(&conn.pendingSize, n)
+}
@@ -0,0 +1,169 @@
+package conn
+
+import (
+	"time"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// Priority priority class of link
+type Priority int
+
+const (
+	// PriorityRealtime interactive tunnels like shell and vnc
+	PriorityRealtime Priority = iota
+	// PriorityInteractive default priority
+	PriorityInteractive
+	// PriorityBulk large transfers
+	PriorityBulk
+	priorityCount
+)
+
+// String get priority name
+func (p Priority) String() string {
+	switch p {
+	case PriorityRealtime:
+		return "realtime"
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBulk:
+		return "bulk"
+	default:
+		return "unknown"
+	}
+}
+
+// writeSchedule weighted round robin of write lanes, realtime:interactive:bulk = 4:2:1
+var writeSchedule = []Priority{
+	PriorityRealtime, PriorityInteractive, PriorityRealtime, PriorityBulk,
+	PriorityRealtime, PriorityInteractive, PriorityRealtime,
+}
+
+// SetLinkPriority set priority class of registered link
+func (conn *Conn) SetLinkPriority(id string, class Priority) {
+	if class < 0 || class >= priorityCount {
+		class = PriorityInteractive
+	}
+	conn.Lock()
+	defer conn.Unlock()
+	opt, ok := conn.registry[id]
+	if !ok {
+		return
+	}
+	opt.priority = class
+	conn.registry[id] = opt
+}
+
+// linkPriority get priority of link, messages without link are realtime
+func (conn *Conn) linkPriority(id string) Priority {
+	if len(id) == 0 {
+		return PriorityRealtime
+	}
+	conn.RLock()
+	defer conn.RUnlock()
+	opt, ok := conn.registry[id]
+	if !ok {
+		return PriorityInteractive
+	}
+	return opt.priority
+}
+
+// lane get write lane of message
+func (conn *Conn) lane(msg *network.Msg) chan *network.Msg {
+	return conn.write[conn.linkPriority(msg.GetLinkId())]
+}
+
+// nextWrite get next message to write by weighted round robin,
+// the rest messages of batch are returned first, returns nil if connection closed
+func (conn *Conn) nextWrite() *network.Msg {
+	if len(conn.batching) > 0 {
+		msg := conn.batching[0]
+		conn.batching = conn.batching[1:]
+		return msg
+	}
+	conn.writeIdx = (conn.writeIdx + 1) % len(writeSchedule)
+	first := writeSchedule[conn.writeIdx]
+	select {
+	case msg := <-conn.write[first]:
+		return msg
+	default:
+	}
+	select {
+	case msgs := <-conn.batch:
+		return conn.startBatch(msgs)
+	default:
+	}
+	for p := PriorityRealtime; p < priorityCount; p++ {
+		select {
+		case msg := <-conn.write[p]:
+			return msg
+		default:
+		}
+	}
+	select {
+	case msg := <-conn.write[PriorityRealtime]:
+		return msg
+	case msg := <-conn.write[PriorityInteractive]:
+		return msg
+	case msg := <-conn.write[PriorityBulk]:
+		return msg
+	case msgs := <-conn.batch:
+		return conn.startBatch(msgs)
+	case <-conn.ctx.Done():
+		return nil
+	}
+}
+
+// deliverTimeout lower priority links wait less time when the consumer
+// is slow so that the read loop is not blocked for other links
+func (conn *Conn) deliverTimeout(id string) time.Duration {
+	switch conn.linkPriority(id) {
+	case PriorityBulk:
+		return conn.cfg.ReadTimeout / 4
+	case PriorityInteractive:
+		return conn.cfg.ReadTimeout / 2
+	default:
+		return conn.cfg.ReadTimeout
+	}
+}
+
+// drainWrite discard messages left in write queue after the write loop exited
+func (conn *Conn) drainWrite() {
+	drop := func(msg *network.Msg) {
+		conn.addPending(msg, -1)
+		conn.cancelled(msg)
+		conn.stale(msg)
+	}
+	for _, msg := range conn.batching {
+		drop(msg)
+	}
+	conn.batching = nil
+	for {
+		select {
+		case msg := <-conn.write[PriorityRealtime]:
+			drop(msg)
+		case msg := <-conn.write[PriorityInteractive]:
+			drop(msg)
+		case msg := <-conn.write[PriorityBulk]:
+			drop(msg)
+		case msgs := <-conn.batch:
+			for _, msg := range msgs {
+				drop(msg)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// wirePriority get relay priority of message by link priority
+func (conn *Conn) wirePriority(msg *network.Msg) network.MsgPriority {
+	switch conn.linkPriority(msg.GetLinkId()) {
+	case PriorityRealtime:
+		return network.Msg_high
+	case PriorityBulk:
+		return network.Msg_low
+	default:
+		return network.Msg_normal
+	}
+}
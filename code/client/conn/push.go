@@ -0,0 +1,118 @@
+package conn
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lwch/natpass/code/client/global"
+	"github.com/lwch/natpass/code/network"
+)
+
+// pushable fields of configure which the server can change on the fly,
+// other fields are refused for safety
+var pushable = map[string]func(cfg *global.Configure, value string) error{
+	"servers": func(cfg *global.Configure, value string) error {
+		var servers []string
+		for _, server := range strings.Split(value, ",") {
+			server = strings.TrimSpace(server)
+			if len(server) == 0 {
+				continue
+			}
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				return err
+			}
+			servers = append(servers, server)
+		}
+		if len(servers) == 0 {
+			return errors.New("empty")
+		}
+		cfg.Servers = servers
+		return nil
+	},
+	"max_links": func(cfg *global.Configure, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if n <= 0 {
+			return errors.New("must be positive")
+		}
+		cfg.MaxLinks = n
+		return nil
+	},
+	"unknown_rate": func(cfg *global.Configure, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		cfg.UnknownRate = n
+		return nil
+	},
+}
+
+// onConfig apply configure pushed by server and acknowledge the applied and refused fields
+func (conn *Conn) onConfig(msg *network.Msg) {
+	values := msg.GetCfg().GetValues()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	next := *conn.cfg
+	next.Servers = conn.scores.list()
+	next.MaxLinks = int(atomic.LoadInt64(&conn.maxLinks))
+	next.UnknownRate = int(atomic.LoadInt64(&conn.unknownMax))
+	var applied, refused []string
+	var keepalive time.Duration
+	for _, key := range keys {
+		value := values[key]
+		var err error
+		switch key {
+		case "keepalive_interval":
+			keepalive, err = time.ParseDuration(value)
+			if err == nil && keepalive <= 0 {
+				err = errors.New("must be positive")
+			}
+		default:
+			fn := pushable[key]
+			if fn == nil {
+				err = errors.New("not remotely mutable")
+				break
+			}
+			err = fn(&next, value)
+		}
+		if err != nil {
+			conn.logError("refuse pushed configure %s=%s: %v", key, value, err)
+			refused = append(refused, key)
+			continue
+		}
+		conn.logInfo("apply pushed configure %s=%s", key, value)
+		applied = append(applied, key)
+	}
+	if len(applied) > 0 {
+		conn.UpdateConfig(&next)
+		if keepalive > 0 {
+			conn.SetKeepaliveInterval(keepalive)
+		}
+	}
+
+	var ack network.Msg
+	ack.To = msg.GetFrom()
+	ack.XType = network.Msg_config_ack
+	ack.ReqId = msg.GetReqId()
+	ack.Payload = &network.Msg_Cfg{
+		Cfg: &network.ConfigUpdate{
+			Applied: applied,
+			Refused: refused,
+		},
+	}
+	if err := conn.Send(&ack); err != nil {
+		conn.logError("send config ack %d: %v", msg.GetReqId(), err)
+	}
+}
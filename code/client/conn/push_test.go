@@ -0,0 +1,82 @@
+package conn
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lwch/natpass/code/client/global"
+	"github.com/lwch/natpass/code/network"
+)
+
+func configMsg(from string, values map[string]string) *network.Msg {
+	return &network.Msg{
+		From:  from,
+		To:    "me",
+		XType: network.Msg_config,
+		Payload: &network.Msg_Cfg{
+			Cfg: &network.ConfigUpdate{Values: values},
+		},
+	}
+}
+
+func TestConfigFromPeerDropped(t *testing.T) {
+	conn := newTestConn(t, nil)
+	conn.handle(configMsg("peer", map[string]string{"servers": "10.0.0.1:6154"}))
+	if servers := conn.scores.list(); !reflect.DeepEqual(servers, []string{"127.0.0.1:6154"}) {
+		t.Fatalf("servers changed by peer: %v", servers)
+	}
+}
+
+func TestConfigReleased(t *testing.T) {
+	conn := newTestConn(t, func(cfg *global.Configure) {
+		cfg.ReadPool = true
+	})
+	for _, from := range []string{"peer", "server"} {
+		msg := configMsg(from, map[string]string{"unknown_rate": "1"})
+		conn.handle(msg)
+		// released message is reset
+		if msg.GetXType() != network.Msg_unknown {
+			t.Fatalf("configure from %s not released", from)
+		}
+	}
+}
+
+func TestConfigFromServerApplied(t *testing.T) {
+	conn := newTestConn(t, nil)
+	conn.handle(configMsg("server", map[string]string{"servers": "10.0.0.1:6154"}))
+	if servers := conn.scores.list(); !reflect.DeepEqual(servers, []string{"10.0.0.1:6154"}) {
+		t.Fatalf("servers not applied: %v", servers)
+	}
+}
+
+func TestConfigLimitsNotShared(t *testing.T) {
+	conn := newTestConn(t, func(cfg *global.Configure) {
+		cfg.MaxLinks = 10
+		cfg.UnknownRate = 100
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			conn.unknownAllowed("peer")
+		}
+	}()
+	conn.handle(configMsg("server", map[string]string{"unknown_rate": "1"}))
+	conn.handle(configMsg("server", map[string]string{"max_links": "1"}))
+	<-done
+	if conn.cfg.MaxLinks != 10 || conn.cfg.UnknownRate != 100 {
+		t.Fatalf("shared configure changed: max_links=%d, unknown_rate=%d",
+			conn.cfg.MaxLinks, conn.cfg.UnknownRate)
+	}
+	// max_links pushed later keeps the pushed unknown_rate
+	if n := atomic.LoadInt64(&conn.unknownMax); n != 1 {
+		t.Fatalf("unknown_rate not applied: %d", n)
+	}
+	if err := conn.autoRegister(&network.Msg{LinkId: "l1", XType: network.Msg_forward}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.autoRegister(&network.Msg{LinkId: "l2", XType: network.Msg_forward}); err != ErrTooManyLinks {
+		t.Fatalf("max_links not applied: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package conn
+
+import (
+	"time"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// ReadOne read one message from link with timeout
+func (conn *Conn) ReadOne(id string, timeout time.Duration) (*network.Msg, error) {
+	ch, ok := conn.ChanReadOK(id)
+	if !ok {
+		return nil, ErrLinkNotFound
+	}
+	select {
+	case msg := <-ch:
+		return msg, nil
+	case <-time.After(timeout):
+		return nil, ErrTimeout
+	case <-conn.ctx.Done():
+		return nil, ErrClosed
+	}
+}
+
+// LinkQueueDepths get buffered message count of each link
+func (conn *Conn) LinkQueueDepths() map[string]int {
+	conn.RLock()
+	defer conn.RUnlock()
+	ret := make(map[string]int, len(conn.read))
+	for id, ch := range conn.read {
+		ret[id] = len(ch)
+	}
+	return ret
+}
+
+// DrainLink pull all buffered messages of link without blocking,
+// call it before RemoveLink to process or persist the unread messages
+func (conn *Conn) DrainLink(id string) []*network.Msg {
+	ch, ok := conn.ChanReadOK(id)
+	if !ok {
+		return nil
+	}
+	var ret []*network.Msg
+	for {
+		select {
+		case msg := <-ch:
+			ret = append(ret, msg)
+		default:
+			return ret
+		}
+	}
+}
+
+// Inject push message into read path as if it is received from server,
+// used to replay captured messages for debugging
+func (conn *Conn) Inject(msg *network.Msg) {
+	conn.handle(msg)
+}
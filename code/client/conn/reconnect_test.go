@@ -0,0 +1,97 @@
+package conn
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lwch/natpass/code/client/global"
+	"github.com/lwch/natpass/code/network"
+)
+
+func TestTryConnectStopsWhenClosed(t *testing.T) {
+	conn := newTestConn(t, func(cfg *global.Configure) {
+		// nothing listens on port 1
+		cfg.Servers = []string{"127.0.0.1:1"}
+		cfg.HandshakeTimeout = time.Second
+		cfg.RetryOn = []string{global.RetryDial}
+		cfg.ReconnectThreshold = 100
+		cfg.ReconnectPolicy = global.ReconnectPolicy{
+			Initial:    time.Minute,
+			Multiplier: 1,
+			Max:        time.Minute,
+		}
+	})
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.tryConnect()
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	conn.cancel()
+	select {
+	case err := <-done:
+		if err != ErrClosed {
+			t.Fatalf("want ErrClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("tryConnect not stopped after closed")
+	}
+}
+
+// pipeConn create connection whose written data is counted by n
+func pipeConn(t *testing.T, n *int64) *network.Conn {
+	a, b := net.Pipe()
+	cn := network.NewConn(a)
+	t.Cleanup(cn.Close)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			m, err := b.Read(buf)
+			atomic.AddInt64(n, int64(m))
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return cn
+}
+
+// TestConnSwapRace must be run with -race, the underlying connection is
+// replaced while loopWrite and keepalive are using it
+func TestConnSwapRace(t *testing.T) {
+	conn := newTestConn(t, nil)
+	var written int64
+	conn.setConn(pipeConn(t, &written))
+	go conn.loopWrite()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			conn.setConn(pipeConn(t, &written))
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			if err := conn.send(conn.keepaliveMsg()); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt64(&written) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if atomic.LoadInt64(&written) == 0 {
+		t.Fatal("nothing written to connections")
+	}
+}
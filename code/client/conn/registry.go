@@ -0,0 +1,74 @@
+package conn
+
+import (
+	"sync/atomic"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+const linkBufferSize = 10
+
+// linkOptions options of registered link, used to restore link after reconnect
+type linkOptions struct {
+	size     int
+	types    []network.MsgType
+	priority Priority
+	linkType string // type of payload codec
+}
+
+// OnReconnect add callback after reconnected, ids are the registered links,
+// the owners should re-drive their protocol setup
+func (conn *Conn) OnReconnect(fn func(ids []string)) {
+	conn.Lock()
+	conn.onReconnect = append(conn.onReconnect, fn)
+	conn.Unlock()
+}
+
+// restoreLinks recreate channels of registered links and notify owners
+func (conn *Conn) restoreLinks() {
+	conn.Lock()
+	ids := make([]string, 0, len(conn.registry))
+	for id, opt := range conn.registry {
+		if _, ok := conn.read[id]; !ok {
+			conn.logInfo("restore link %s", id)
+			conn.read[id] = make(chan *network.Msg, opt.size)
+		}
+		ids = append(ids, id)
+	}
+	fns := conn.onReconnect
+	conn.Unlock()
+	for _, fn := range fns {
+		fn(ids)
+	}
+}
+
+// autoRegister register link with default options when sending to an unregistered link,
+// so the reply from peer can be read
+func (conn *Conn) autoRegister(msg *network.Msg) error {
+	id := msg.GetLinkId()
+	if len(id) == 0 ||
+		msg.GetXType() == network.Msg_disconnect ||
+		msg.GetXType() == network.Msg_migrate {
+		return nil
+	}
+	conn.Lock()
+	defer conn.Unlock()
+	if _, ok := conn.registry[id]; ok {
+		return nil
+	}
+	if int64(len(conn.registry)) >= atomic.LoadInt64(&conn.maxLinks) {
+		return ErrTooManyLinks
+	}
+	if err := conn.allowLink(id); err != nil {
+		return err
+	}
+	conn.logInfo("auto register link %s", id)
+	if _, ok := conn.read[id]; !ok {
+		conn.read[id] = make(chan *network.Msg, linkBufferSize)
+	}
+	conn.registry[id] = linkOptions{
+		size:     linkBufferSize,
+		priority: PriorityInteractive,
+	}
+	return nil
+}
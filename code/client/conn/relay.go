@@ -0,0 +1,64 @@
+package conn
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// dialRelay connect to server through relay_chain, each relay is asked to connect
+// to the next hop and the connection is spliced after acknowledged,
+// the tls is started on each hop if ssl is enabled
+func (conn *Conn) dialRelay(ctx context.Context, addr string) (net.Conn, error) {
+	chain := conn.cfg.RelayChain
+	dial, err := conn.dialTCP(ctx, chain[0])
+	if err != nil {
+		return nil, err
+	}
+	for i, relay := range chain {
+		next := addr
+		if i+1 < len(chain) {
+			next = chain[i+1]
+		}
+		dial, err = conn.relayTo(ctx, dial, relay, next)
+		if err != nil {
+			return nil, err
+		}
+		dial, err = conn.handshakeTLS(ctx, dial, next)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dial, nil
+}
+
+// relayTo request relay to connect to next hop
+func (conn *Conn) relayTo(ctx context.Context, dial net.Conn, relay, next string) (net.Conn, error) {
+	cn := network.NewConn(dial)
+	deadline, _ := ctx.Deadline()
+	var msg network.Msg
+	msg.XType = network.Msg_relay
+	msg.ReqId = atomic.AddUint64(&conn.reqID, 1)
+	msg.From = conn.cfg.ID
+	msg.To = "server"
+	msg.Payload = &network.Msg_Hsp{
+		Hsp: &network.HandshakePayload{
+			Enc:   conn.cfg.Enc[:],
+			Relay: next,
+		},
+	}
+	err := cn.WriteMessage(&msg, time.Until(deadline))
+	if err == nil {
+		_, err = waitHandshakeAck(cn, msg.ReqId, time.Until(deadline))
+	}
+	if err != nil {
+		cn.Close()
+		conn.logError("relay to %s by %s: %v", next, relay, err)
+		return nil, newConnectError(ErrHandshake, relay, err)
+	}
+	conn.logInfo("relay to %s by %s", next, relay)
+	return cn.Detach(), nil
+}
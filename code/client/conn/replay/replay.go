@@ -0,0 +1,59 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/lwch/natpass/code/client/conn"
+	"github.com/lwch/natpass/code/network"
+	"google.golang.org/protobuf/proto"
+)
+
+var errNoPayload = errors.New("no received message with payload, enable message_log.payload")
+
+// Replay read message log and inject the received messages into read path of conn
+// with original relative timing, only records with payload are replayed
+func Replay(r io.Reader, c *conn.Conn) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var last time.Time
+	var replayed int
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		// skip time prefix of log line
+		n := bytes.IndexByte(line, '{')
+		if n == -1 {
+			continue
+		}
+		var record conn.MessageRecord
+		err := json.Unmarshal(line[n:], &record)
+		if err != nil {
+			return err
+		}
+		if record.Dir != "recv" || len(record.Payload) == 0 {
+			continue
+		}
+		var msg network.Msg
+		err = proto.Unmarshal(record.Payload, &msg)
+		if err != nil {
+			return err
+		}
+		if !last.IsZero() && record.Time.After(last) {
+			time.Sleep(record.Time.Sub(last))
+		}
+		last = record.Time
+		c.Inject(&msg)
+		replayed++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if replayed == 0 {
+		return errNoPayload
+	}
+	return nil
+}
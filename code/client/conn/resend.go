@@ -0,0 +1,80 @@
+package conn
+
+import (
+	"github.com/lwch/natpass/code/network"
+	"google.golang.org/protobuf/proto"
+)
+
+// resendBuffer ring buffer of recently sent messages on link
+type resendBuffer struct {
+	data []*network.Msg
+	next int
+}
+
+func (buf *resendBuffer) add(msg *network.Msg) {
+	if len(buf.data) < cap(buf.data) {
+		buf.data = append(buf.data, msg)
+		return
+	}
+	buf.data[buf.next] = msg
+	buf.next = (buf.next + 1) % len(buf.data)
+}
+
+// EnableResend keep the last n sent messages on link to satisfy resend requests from peer,
+// it is disabled when the link is removed
+func (conn *Conn) EnableResend(id string, n int) {
+	if n <= 0 {
+		return
+	}
+	conn.lockResend.Lock()
+	defer conn.lockResend.Unlock()
+	conn.resend[id] = &resendBuffer{data: make([]*network.Msg, 0, n)}
+}
+
+// RequestResend request peer to resend messages on link with sequence id in [from, to]
+func (conn *Conn) RequestResend(to, id string, from, until uint64) error {
+	var msg network.Msg
+	msg.To = to
+	msg.XType = network.Msg_resend
+	msg.LinkId = id
+	msg.Payload = &network.Msg_Rsend{
+		Rsend: &network.ResendRequest{
+			From: from,
+			To:   until,
+		},
+	}
+	return conn.Send(&msg)
+}
+
+// recordSent save a copy of message before it is transformed
+func (conn *Conn) recordSent(msg *network.Msg) {
+	conn.lockResend.Lock()
+	defer conn.lockResend.Unlock()
+	buf := conn.resend[msg.GetLinkId()]
+	if buf == nil || msg.GetXType() == network.Msg_resend {
+		return
+	}
+	buf.add(proto.Clone(msg).(*network.Msg))
+}
+
+// onResend resend messages requested by peer, the sequence ids are kept
+func (conn *Conn) onResend(msg *network.Msg) {
+	req := msg.GetRsend()
+	var msgs []*network.Msg
+	conn.lockResend.Lock()
+	if buf := conn.resend[msg.GetLinkId()]; buf != nil {
+		for _, m := range buf.data {
+			if m.GetSeq() >= req.GetFrom() && m.GetSeq() <= req.GetTo() {
+				msgs = append(msgs, proto.Clone(m).(*network.Msg))
+			}
+		}
+	}
+	conn.lockResend.Unlock()
+	conn.logInfo("resend %d messages on link %s to %s, seq=[%d, %d]",
+		len(msgs), msg.GetLinkId(), msg.GetFrom(), req.GetFrom(), req.GetTo())
+	go func() {
+		for _, m := range msgs {
+			conn.Send(m)
+		}
+	}()
+}
@@ -0,0 +1,48 @@
+package conn
+
+import "github.com/lwch/natpass/code/network"
+
+// PeerLinkKey get link key of peer, register link by this key to receive messages
+// only from the peer when route_by_peer is enabled
+func PeerLinkKey(from, id string) string {
+	return from + "/" + id
+}
+
+// routeKey get key of read map, the (from, link id) key is used if it is registered
+// when route_by_peer is enabled, otherwise the link id is used
+func (conn *Conn) routeKey(msg *network.Msg) string {
+	id := msg.GetLinkId()
+	if !conn.cfg.RouteByPeer {
+		return id
+	}
+	key := PeerLinkKey(msg.GetFrom(), id)
+	conn.RLock()
+	_, ok := conn.read[key]
+	conn.RUnlock()
+	if ok {
+		return key
+	}
+	return id
+}
+
+// SetRouter set hook to rewrite destination of outbound messages before written,
+// e.g. map logical names to peer ids, the destination is not changed if it returns
+// empty string, nil to disable
+func (conn *Conn) SetRouter(fn func(msg *network.Msg) string) {
+	conn.Lock()
+	conn.router = fn
+	conn.Unlock()
+}
+
+// route resolve destination of outbound message by router
+func (conn *Conn) route(msg *network.Msg) {
+	conn.RLock()
+	fn := conn.router
+	conn.RUnlock()
+	if fn == nil {
+		return
+	}
+	if to := fn(msg); len(to) > 0 {
+		msg.To = to
+	}
+}
@@ -0,0 +1,47 @@
+package conn
+
+import (
+	"sync"
+	"time"
+)
+
+// rttHistorySize samples of keepalive rtt kept, 1 hour by 10 seconds keepalive
+const rttHistorySize = 360
+
+// RTTSample keepalive rtt sample, Reconnect marks the boundary of a new connection
+// and its RTT is zero
+type RTTSample struct {
+	Time      time.Time     `json:"time"`
+	RTT       time.Duration `json:"rtt"`
+	Server    string        `json:"server"`
+	Reconnect bool          `json:"reconnect,omitempty"`
+}
+
+// rttHistory ring buffer of rtt samples, it is kept across reconnects
+type rttHistory struct {
+	sync.Mutex
+	data []RTTSample
+	next int
+}
+
+func (h *rttHistory) add(sample RTTSample) {
+	h.Lock()
+	defer h.Unlock()
+	if len(h.data) < rttHistorySize {
+		h.data = append(h.data, sample)
+		return
+	}
+	h.data[h.next] = sample
+	h.next = (h.next + 1) % rttHistorySize
+}
+
+// RTTHistory get keepalive rtt samples in time order with reconnect markers
+func (conn *Conn) RTTHistory() []RTTSample {
+	h := &conn.rttHistory
+	h.Lock()
+	defer h.Unlock()
+	ret := make([]RTTSample, 0, len(h.data))
+	ret = append(ret, h.data[h.next:]...)
+	ret = append(ret, h.data[:h.next]...)
+	return ret
+}
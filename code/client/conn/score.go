@@ -0,0 +1,120 @@
+package conn
+
+import (
+	"sync"
+	"time"
+)
+
+// weight of the newest sample in moving average
+const scoreAlpha = 0.2
+
+type serverScore struct {
+	rtt      time.Duration // moving average of keepalive rtt
+	failRate float64       // moving average of connect failure rate
+}
+
+// score higher is better, servers never connected have the highest score
+func (s *serverScore) score() float64 {
+	return (1 - s.failRate) / (1 + s.rtt.Seconds())
+}
+
+type scores struct {
+	sync.RWMutex
+	servers []string
+	data    map[string]*serverScore
+}
+
+func newScores(servers []string) *scores {
+	data := make(map[string]*serverScore, len(servers))
+	for _, s := range servers {
+		data[s] = new(serverScore)
+	}
+	return &scores{servers: servers, data: data}
+}
+
+// best get the highest scored server, the first server is preferred on ties
+func (s *scores) best() string {
+	s.RLock()
+	defer s.RUnlock()
+	var ret string
+	var max float64 = -1
+	for _, addr := range s.servers {
+		n := s.data[addr].score()
+		if n > max {
+			ret = addr
+			max = n
+		}
+	}
+	return ret
+}
+
+func (s *scores) connected(addr string, ok bool) {
+	s.Lock()
+	defer s.Unlock()
+	data := s.data[addr]
+	if data == nil {
+		return
+	}
+	var sample float64
+	if !ok {
+		sample = 1
+	}
+	data.failRate = data.failRate*(1-scoreAlpha) + sample*scoreAlpha
+}
+
+func (s *scores) rtt(addr string, d time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	data := s.data[addr]
+	if data == nil {
+		return
+	}
+	if data.rtt == 0 {
+		data.rtt = d
+		return
+	}
+	data.rtt = time.Duration(float64(data.rtt)*(1-scoreAlpha) + float64(d)*scoreAlpha)
+}
+
+func (s *scores) all() map[string]float64 {
+	s.RLock()
+	defer s.RUnlock()
+	ret := make(map[string]float64, len(s.data))
+	for addr, data := range s.data {
+		ret[addr] = data.score()
+	}
+	return ret
+}
+
+// ServerScores get health score of each server, higher is better
+func (conn *Conn) ServerScores() map[string]float64 {
+	return conn.scores.all()
+}
+
+// CurrentServer get the server address of current connection
+func (conn *Conn) CurrentServer() string {
+	addr, _ := conn.server.Load().(string)
+	return addr
+}
+
+func (s *scores) list() []string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.servers
+}
+
+// update replace server list, the scores of existing servers are kept
+func (s *scores) update(servers []string) {
+	s.Lock()
+	defer s.Unlock()
+	data := make(map[string]*serverScore, len(servers))
+	for _, addr := range servers {
+		if old, ok := s.data[addr]; ok {
+			data[addr] = old
+		} else {
+			data[addr] = new(serverScore)
+		}
+	}
+	s.servers = servers
+	s.data = data
+}
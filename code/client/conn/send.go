@@ -3,16 +3,128 @@ package conn
 import (
 	"time"
 
+	"github.com/lwch/natpass/code/client/global"
 	"github.com/lwch/natpass/code/network"
 )
 
+// Send send message to write queue, when the queue is full:
+//   - block: wait until write timeout, no message is lost but the caller may be slow
+//   - error: returns ErrWriteFull immediately, the caller can shed load
+//   - drop-oldest: drop the oldest message in queue, the newest data is always sent
+//
+// when auto_register is enabled, sending to an unregistered link registers it,
+// returns ErrTooManyLinks if max_links is reached, returns ErrLinkRateLimited if
+// link_rate is exceeded, returns ErrBufferFull if buffered messages exceeded max_buffer,
+// returns ErrWindowFull if window of link is full, returns ErrClosed if the connection
+// is closed or closing while waiting
+//
+// messages of one link are written in FIFO order of Send calls, concurrent senders of
+// a link are queued one by one in order of arrival so a slow sender delays the others.
+// the order is not kept with WriteBatch, control messages sent on control connection
+// and messages queued before SetLinkPriority changed the lane
+//
+// while paused application messages are queued until Resume by pause policy queue,
+// returns ErrPaused by pause policy reject or the pause queue is full
+func (conn *Conn) Send(msg *network.Msg) error {
+	if other := conn.getMigrated(); other != nil {
+		return other.Send(msg)
+	}
+	if held, err := conn.holdSend(msg); held {
+		return err
+	}
+	return conn.send(msg)
+}
+
+func (conn *Conn) send(msg *network.Msg) error {
+	if conn.cfg.AutoRegister {
+		if err := conn.autoRegister(msg); err != nil {
+			return err
+		}
+	}
+	if conn.ctx.Err() != nil {
+		return ErrClosed
+	}
+	defer conn.orderLink(msg.GetLinkId())()
+	if !conn.waitBuffer(conn.cfg.WriteTimeout) {
+		return ErrBufferFull
+	}
+	if err := conn.waitWindow(msg.GetLinkId()); err != nil {
+		return err
+	}
+	write := conn.lane(msg)
+	conn.addPending(msg, 1)
+	switch conn.cfg.WriteFullPolicy {
+	case global.WriteFullError:
+		select {
+		case write <- msg:
+			return nil
+		default:
+			conn.addPending(msg, -1)
+			return ErrWriteFull
+		}
+	case global.WriteFullDropOldest:
+		for {
+			select {
+			case write <- msg:
+				return nil
+			case <-conn.ctx.Done():
+				conn.addPending(msg, -1)
+				return ErrClosed
+			default:
+			}
+			select {
+			case old := <-write:
+				conn.addPending(old, -1)
+				conn.cancelled(old)
+				conn.stale(old)
+				conn.logError("write queue full, drop message %s on link %s",
+					old.GetXType().String(), old.GetLinkId())
+			default:
+			}
+		}
+	default:
+		select {
+		case write <- msg:
+			return nil
+		case <-time.After(conn.cfg.WriteTimeout):
+			conn.addPending(msg, -1)
+			return ErrTimeout
+		case <-conn.ctx.Done():
+			conn.addPending(msg, -1)
+			return ErrClosed
+		}
+	}
+}
+
 // SendKeepalive send keepalive message
 func (conn *Conn) SendKeepalive() {
+	conn.Send(conn.keepaliveMsg())
+}
+
+// SetKeepaliveBuilder override the keepalive message for compatible server implementations,
+// nil to restore the default keepalive message
+func (conn *Conn) SetKeepaliveBuilder(fn func() *network.Msg) {
+	conn.Lock()
+	conn.keepaliveFn = fn
+	conn.Unlock()
+}
+
+func (conn *Conn) keepaliveMsg() *network.Msg {
+	conn.RLock()
+	fn := conn.keepaliveFn
+	conn.RUnlock()
+	if fn != nil {
+		return fn()
+	}
 	var msg network.Msg
 	msg.To = "server"
 	msg.XType = network.Msg_keepalive
-	select {
-	case conn.write <- &msg:
-	case <-time.After(conn.cfg.WriteTimeout):
+	if conn.cfg.KeepalivePayloadSize > 0 {
+		msg.Payload = &network.Msg_XData{
+			XData: &network.Data{
+				Data: make([]byte, conn.cfg.KeepalivePayloadSize),
+			},
+		}
 	}
+	return &msg
 }
@@ -1,8 +1,6 @@
 package conn
 
 import (
-	"time"
-
 	"github.com/lwch/natpass/code/client/global"
 	"github.com/lwch/natpass/code/network"
 	"google.golang.org/protobuf/proto"
@@ -54,10 +52,7 @@ func (conn *Conn) SendConnectReq(id string, cfg global.Rule) {
 			},
 		}
 	}
-	select {
-	case conn.write <- &msg:
-	case <-time.After(conn.cfg.WriteTimeout):
-	}
+	conn.Send(&msg)
 }
 
 // SendConnectVnc send connect vnc request message
@@ -85,10 +80,7 @@ func (conn *Conn) SendConnectVnc(id string, cfg global.Rule, quality uint64, sho
 			},
 		},
 	}
-	select {
-	case conn.write <- &msg:
-	case <-time.After(conn.cfg.WriteTimeout):
-	}
+	conn.Send(&msg)
 }
 
 // SendDisconnect send disconnect message
@@ -97,13 +89,10 @@ func (conn *Conn) SendDisconnect(to string, id string) uint64 {
 	msg.To = to
 	msg.XType = network.Msg_disconnect
 	msg.LinkId = id
-	select {
-	case conn.write <- &msg:
-		data, _ := proto.Marshal(&msg)
-		return uint64(len(data))
-	case <-time.After(conn.cfg.WriteTimeout):
+	if conn.Send(&msg) != nil {
 		return 0
 	}
+	return uint64(proto.Size(&msg))
 }
 
 // SendConnectError send connect error response message
@@ -118,10 +107,7 @@ func (conn *Conn) SendConnectError(to string, id, info string) {
 			Msg: info,
 		},
 	}
-	select {
-	case conn.write <- &msg:
-	case <-time.After(conn.cfg.WriteTimeout):
-	}
+	conn.Send(&msg)
 }
 
 // SendConnectOK send connect success response message
@@ -135,8 +121,5 @@ func (conn *Conn) SendConnectOK(to string, id string) {
 			Ok: true,
 		},
 	}
-	select {
-	case conn.write <- &msg:
-	case <-time.After(conn.cfg.WriteTimeout):
-	}
+	conn.Send(&msg)
 }
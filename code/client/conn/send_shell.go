@@ -1,19 +1,20 @@
 package conn
 
 import (
-	"time"
-
 	"github.com/lwch/natpass/code/network"
 	"google.golang.org/protobuf/proto"
 )
 
-// SendShellData send shell data
+// SendShellData send shell data, the data is buffered if coalescing is enabled on link
 func (conn *Conn) SendShellData(to string, id string, data []byte) uint64 {
 	dup := func(data []byte) []byte {
 		ret := make([]byte, len(data))
 		copy(ret, data)
 		return ret
 	}
+	if conn.coalesceData(to, id, data) {
+		return uint64(len(data))
+	}
 	var msg network.Msg
 	msg.To = to
 	msg.XType = network.Msg_shell_data
@@ -23,17 +24,15 @@ func (conn *Conn) SendShellData(to string, id string, data []byte) uint64 {
 			Data: dup(data),
 		},
 	}
-	select {
-	case conn.write <- &msg:
-		data, _ := proto.Marshal(&msg)
-		return uint64(len(data))
-	case <-time.After(conn.cfg.WriteTimeout):
+	if conn.Send(&msg) != nil {
 		return 0
 	}
+	return uint64(proto.Size(&msg))
 }
 
 // SendShellResize send shell resize
 func (conn *Conn) SendShellResize(to string, id string, rows, cols uint32) {
+	conn.flushLink(id)
 	var msg network.Msg
 	msg.To = to
 	msg.XType = network.Msg_shell_resize
@@ -44,8 +43,5 @@ func (conn *Conn) SendShellResize(to string, id string, rows, cols uint32) {
 			Cols: cols,
 		},
 	}
-	select {
-	case conn.write <- &msg:
-	case <-time.After(conn.cfg.WriteTimeout):
-	}
+	conn.Send(&msg)
 }
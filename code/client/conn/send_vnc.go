@@ -2,7 +2,6 @@ package conn
 
 import (
 	"image"
-	"time"
 
 	"github.com/lwch/natpass/code/network"
 )
@@ -33,10 +32,7 @@ func (conn *Conn) SendVNCImage(to string, id string, screen, rect image.Rectangl
 			Data:   dup(data),
 		},
 	}
-	select {
-	case conn.write <- &msg:
-	case <-time.After(conn.cfg.WriteTimeout):
-	}
+	conn.Send(&msg)
 }
 
 // SendVNCCtrl send vnc config
@@ -51,10 +47,7 @@ func (conn *Conn) SendVNCCtrl(to string, id string, quality uint64, showCursor b
 			Cursor:  showCursor,
 		},
 	}
-	select {
-	case conn.write <- &msg:
-	case <-time.After(conn.cfg.WriteTimeout):
-	}
+	conn.Send(&msg)
 }
 
 // SendVNCMouse send vnc mouse event
@@ -88,10 +81,7 @@ func (conn *Conn) SendVNCMouse(to string, id string,
 			Y:    uint32(y),
 		},
 	}
-	select {
-	case conn.write <- &msg:
-	case <-time.After(conn.cfg.WriteTimeout):
-	}
+	conn.Send(&msg)
 }
 
 // SendVNCKeyboard send vnc keyboard event
@@ -114,10 +104,7 @@ func (conn *Conn) SendVNCKeyboard(to string, id string,
 			Key:  key,
 		},
 	}
-	select {
-	case conn.write <- &msg:
-	case <-time.After(conn.cfg.WriteTimeout):
-	}
+	conn.Send(&msg)
 }
 
 // SendVNCCADEvent send vnc keyboard event
@@ -126,10 +113,7 @@ func (conn *Conn) SendVNCCADEvent(to string, id string) {
 	msg.To = to
 	msg.XType = network.Msg_vnc_cad
 	msg.LinkId = id
-	select {
-	case conn.write <- &msg:
-	case <-time.After(conn.cfg.WriteTimeout):
-	}
+	conn.Send(&msg)
 }
 
 // SendVNCScroll send vnc scroll event
@@ -144,10 +128,7 @@ func (conn *Conn) SendVNCScroll(to string, id string, x, y int32) {
 			Y: y,
 		},
 	}
-	select {
-	case conn.write <- &msg:
-	case <-time.After(conn.cfg.WriteTimeout):
-	}
+	conn.Send(&msg)
 }
 
 // SendVNCClipboardData send vnc clipboard data
@@ -165,8 +146,5 @@ func (conn *Conn) SendVNCClipboardData(to string, id string, set bool, data stri
 			},
 		},
 	}
-	select {
-	case conn.write <- &msg:
-	case <-time.After(conn.cfg.WriteTimeout):
-	}
+	conn.Send(&msg)
 }
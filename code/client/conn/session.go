@@ -0,0 +1,69 @@
+package conn
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/lwch/runtime"
+)
+
+const (
+	// sessionLen length of session id prefixed to link id
+	sessionLen = 8
+	// sessionMax max count of previous sessions kept to detect late messages
+	sessionMax = 16
+)
+
+// newSession rotate session id when a new connection is established,
+// the link ids generated by NewLinkID are prefixed by it
+func (conn *Conn) newSession() {
+	id, err := runtime.UUID(sessionLen, "0123456789abcdef")
+	if err != nil {
+		return
+	}
+	conn.lockSession.Lock()
+	conn.sessions = append(conn.sessions, id)
+	if len(conn.sessions) > sessionMax {
+		conn.sessions = conn.sessions[len(conn.sessions)-sessionMax:]
+	}
+	conn.lockSession.Unlock()
+}
+
+// Session get session id of current connection
+func (conn *Conn) Session() string {
+	conn.lockSession.Lock()
+	defer conn.lockSession.Unlock()
+	if len(conn.sessions) == 0 {
+		return ""
+	}
+	return conn.sessions[len(conn.sessions)-1]
+}
+
+// linkSession get session id prefixed to link id, empty if not prefixed
+func linkSession(id string) string {
+	if strings.IndexByte(id, '-') != sessionLen {
+		return ""
+	}
+	return id[:sessionLen]
+}
+
+// isStaleSession check the unregistered link was generated in previous session of this
+// connection, the late messages are counted, links of peers are never stale
+func (conn *Conn) isStaleSession(id string) bool {
+	session := linkSession(id)
+	if len(session) == 0 {
+		return false
+	}
+	conn.lockSession.Lock()
+	defer conn.lockSession.Unlock()
+	for i, s := range conn.sessions {
+		if s == session {
+			if i == len(conn.sessions)-1 {
+				return false
+			}
+			atomic.AddUint64(&conn.lateCount, 1)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package conn
+
+import (
+	"net"
+)
+
+// setSockBuf set socket buffer sizes of raw connection and log the granted sizes,
+// the os may clamp the sizes
+func (conn *Conn) setSockBuf(c net.Conn) {
+	rbuf := int(conn.cfg.SocketReadBuffer.Bytes())
+	wbuf := int(conn.cfg.SocketWriteBuffer.Bytes())
+	if rbuf == 0 && wbuf == 0 {
+		return
+	}
+	tc, ok := c.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	if rbuf > 0 {
+		if err := tc.SetReadBuffer(rbuf); err != nil {
+			conn.logError("set socket read buffer: %v", err)
+		}
+	}
+	if wbuf > 0 {
+		if err := tc.SetWriteBuffer(wbuf); err != nil {
+			conn.logError("set socket write buffer: %v", err)
+		}
+	}
+	r, w := sockBufSize(tc)
+	conn.logInfo("socket buffer: read=%d(want %d), write=%d(want %d)", r, rbuf, w, wbuf)
+}
@@ -0,0 +1,25 @@
+package conn
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// sockBufSize get socket buffer sizes granted by kernel, -1 if unknown
+func sockBufSize(c *net.TCPConn) (int, int) {
+	r, w := -1, -1
+	rc, err := c.SyscallConn()
+	if err != nil {
+		return r, w
+	}
+	rc.Control(func(fd uintptr) {
+		if n, err := unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF); err == nil {
+			r = n
+		}
+		if n, err := unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_SNDBUF); err == nil {
+			w = n
+		}
+	})
+	return r, w
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package conn
+
+import "net"
+
+// sockBufSize socket buffer sizes are not supported to get, returns -1
+func sockBufSize(c *net.TCPConn) (int, int) {
+	return -1, -1
+}
@@ -0,0 +1,50 @@
+package conn
+
+import (
+	"sync/atomic"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// SwitchServer move the connection to addr without dropping links: the new connection
+// is handshaked and warmed up while the old one is still in use, then the links are
+// cut over and the old connection is closed. the current server is replaced by addr in
+// the server list, and the current connection is kept if connect failed
+func (conn *Conn) SwitchServer(addr string) error {
+	var old *network.Conn
+	defer func() {
+		if old != nil {
+			conn.emitDisconnect(old, nil)
+			conn.emitConnect()
+		}
+	}()
+	conn.lockReconnect.Lock()
+	defer conn.lockReconnect.Unlock()
+	cn, err := conn.connectTo(addr, false)
+	if err == nil {
+		err = conn.runWarmup(addr, cn)
+	}
+	if err != nil {
+		conn.logError("switch server to %s: %v", addr, err)
+		return err
+	}
+	from := conn.CurrentServer()
+	servers := []string{addr}
+	for _, server := range conn.scores.list() {
+		if server != from && server != addr {
+			servers = append(servers, server)
+		}
+	}
+	conn.scores.update(servers)
+	conn.scores.connected(addr, true)
+	conn.server.Store(addr)
+	atomic.StoreUint32(&conn.maxSize, cn.MaxSize())
+	conn.breaker.success()
+	old = conn.getConn()
+	conn.setConn(cn)
+	old.Close()
+	conn.failAcks()
+	conn.restoreLinks()
+	conn.logInfo("switched server from %s to %s", from, addr)
+	return nil
+}
@@ -0,0 +1,78 @@
+package conn
+
+import (
+	"io"
+
+	"github.com/lwch/logging"
+	"github.com/lwch/natpass/code/network"
+)
+
+// teeBufferSize buffered records of tee, records are dropped when the writer stalls
+const teeBufferSize = 256
+
+type tee struct {
+	w       io.Writer
+	payload bool
+	ch      chan []byte
+	done    chan struct{}
+}
+
+// TeeLink copy inbound messages of link to w as json records of MessageRecord,
+// the payload is included if includePayload is true, nil w to stop.
+// the copies are dropped if w is slow, the delivery of link is not affected
+func (conn *Conn) TeeLink(id string, w io.Writer, includePayload bool) {
+	var t *tee
+	if w != nil {
+		t = &tee{
+			w:       w,
+			payload: includePayload,
+			ch:      make(chan []byte, teeBufferSize),
+			done:    make(chan struct{}),
+		}
+		go t.loop()
+	}
+	conn.lockTee.Lock()
+	old := conn.tees[id]
+	if t == nil {
+		delete(conn.tees, id)
+	} else {
+		conn.tees[id] = t
+	}
+	conn.lockTee.Unlock()
+	if old != nil {
+		close(old.done)
+	}
+}
+
+// teeMsg copy message to tee of link if exists
+func (conn *Conn) teeMsg(id string, msg *network.Msg) {
+	conn.lockTee.Lock()
+	t := conn.tees[id]
+	conn.lockTee.Unlock()
+	if t == nil {
+		return
+	}
+	line, err := messageRecord(msgLogRecv, msg, t.payload)
+	if err != nil {
+		return
+	}
+	select {
+	case t.ch <- append(line, '\n'):
+	default:
+		conn.logDebug("tee of link %s is full, drop message %s", id, msg.GetXType().String())
+	}
+}
+
+func (t *tee) loop() {
+	for {
+		select {
+		case line := <-t.ch:
+			if _, err := t.w.Write(line); err != nil {
+				logging.Error("write tee: %v", err)
+				return
+			}
+		case <-t.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package conn
+
+import (
+	"errors"
+
+	"github.com/lwch/natpass/code/client/global"
+)
+
+// retriable check connect error is configured in retry_on
+func (conn *Conn) retriable(err error) bool {
+	if errors.Is(err, ErrDuplicateID) {
+		conn.logError("client id %s is used by another running client, stop reconnecting", conn.cfg.ID)
+		return false
+	}
+	// dropped in the middle of handshake is a transient network failure like broken connection
+	if errors.Is(err, ErrDropped) {
+		return true
+	}
+	var class string
+	switch {
+	case errors.Is(err, ErrDial):
+		class = global.RetryDial
+	case errors.Is(err, ErrTLS):
+		class = global.RetryTLS
+	case errors.Is(err, ErrHandshake):
+		class = global.RetryHandshake
+	default:
+		return true
+	}
+	for _, c := range conn.cfg.RetryOn {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// terminalHolder keep the concrete type stored in atomic.Value consistent,
+// the terminal errors are of different types
+type terminalHolder struct {
+	err error
+}
+
+// terminate stop reconnecting and close the connection
+func (conn *Conn) terminate(err error) {
+	conn.logError("connection terminated: %v", err)
+	conn.terminal.Store(terminalHolder{err})
+	conn.cancel()
+	conn.notifyEOF()
+}
+
+// TerminalError get reason of terminated connection, returns nil if it is running
+func (conn *Conn) TerminalError() error {
+	h, _ := conn.terminal.Load().(terminalHolder)
+	return h.err
+}
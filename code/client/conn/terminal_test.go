@@ -0,0 +1,16 @@
+package conn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTerminateTwice(t *testing.T) {
+	conn := newTestConn(t, nil)
+	conn.terminate(ErrDuplicateID)
+	err := &ConnectError{Stage: ErrHandshake, Addr: "127.0.0.1:6154", Err: errors.New("rejected")}
+	conn.terminate(err)
+	if conn.TerminalError() != err {
+		t.Fatalf("unexpected terminal error: %v", conn.TerminalError())
+	}
+}
@@ -0,0 +1,39 @@
+package conn
+
+import (
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// TCPI_OPT_SYN_DATA in linux/tcp.h
+const tcpiOptSynData = 0x20
+
+// tfoControl enable TCP_FASTOPEN_CONNECT, errors are ignored to fallback
+func tfoControl(network, address string, c syscall.RawConn) error {
+	c.Control(func(fd uintptr) {
+		unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN_CONNECT, 1)
+	})
+	return nil
+}
+
+// tfoUsed check the SYN packet was sent with data
+func tfoUsed(c net.Conn) bool {
+	sc, ok := c.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var info *unix.TCPInfo
+	rc.Control(func(fd uintptr) {
+		info, err = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	})
+	if err != nil {
+		return false
+	}
+	return info.Options&tcpiOptSynData != 0
+}
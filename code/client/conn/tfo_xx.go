@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package conn
+
+import (
+	"net"
+	"syscall"
+)
+
+// tfoControl tcp fast open is only supported on linux
+func tfoControl(network, address string, c syscall.RawConn) error {
+	return nil
+}
+
+func tfoUsed(c net.Conn) bool {
+	return false
+}
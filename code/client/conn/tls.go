@@ -0,0 +1,61 @@
+package conn
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+)
+
+// tlsConfig get tls config of server, the session cache is shared across reconnects
+// to resume tls session
+func (conn *Conn) tlsConfig(addr string) *tls.Config {
+	return &tls.Config{
+		ServerName:         serverName(addr),
+		ClientSessionCache: conn.sessionCache,
+	}
+}
+
+// TLSResumed check the tls session of current connection is resumed
+func (conn *Conn) TLSResumed() bool {
+	state, ok := conn.TLSState()
+	return ok && state.DidResume
+}
+
+// TLSState get tls state of current connection, returns false when ssl is not used
+func (conn *Conn) TLSState() (tls.ConnectionState, bool) {
+	state, _ := conn.tlsState.Load().(*tls.ConnectionState)
+	if state == nil {
+		return tls.ConnectionState{}, false
+	}
+	return *state, true
+}
+
+func (conn *Conn) setTLSState(state *tls.ConnectionState) {
+	conn.tlsState.Store(state)
+	if state == nil {
+		return
+	}
+	fingerprint := "none"
+	if len(state.PeerCertificates) > 0 {
+		sum := sha256.Sum256(state.PeerCertificates[0].Raw)
+		fingerprint = hex.EncodeToString(sum[:])
+	}
+	conn.logInfo("tls connected: version=%s, cipher=%s, server_name=%s, fingerprint=%s, resumed=%v",
+		tlsVersionName(state.Version), tls.CipherSuiteName(state.CipherSuite),
+		state.ServerName, fingerprint, state.DidResume)
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS1.0"
+	case tls.VersionTLS11:
+		return "TLS1.1"
+	case tls.VersionTLS12:
+		return "TLS1.2"
+	case tls.VersionTLS13:
+		return "TLS1.3"
+	default:
+		return "unknown"
+	}
+}
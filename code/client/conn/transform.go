@@ -0,0 +1,39 @@
+package conn
+
+import "github.com/lwch/natpass/code/network"
+
+// Transform message transformer, returns error to drop the message
+type Transform func(*network.Msg) (*network.Msg, error)
+
+// AddInboundTransform add transformer for received messages,
+// transformers are applied in registration order
+func (conn *Conn) AddInboundTransform(fn Transform) {
+	conn.lockTransform.Lock()
+	conn.inbound = append(conn.inbound, fn)
+	conn.lockTransform.Unlock()
+}
+
+// AddOutboundTransform add transformer for sending messages,
+// transformers are applied in registration order
+func (conn *Conn) AddOutboundTransform(fn Transform) {
+	conn.lockTransform.Lock()
+	conn.outbound = append(conn.outbound, fn)
+	conn.lockTransform.Unlock()
+}
+
+func (conn *Conn) transform(list *[]Transform, msg *network.Msg) (*network.Msg, error) {
+	conn.lockTransform.RLock()
+	fns := *list
+	conn.lockTransform.RUnlock()
+	var err error
+	for _, fn := range fns {
+		msg, err = fn(msg)
+		if err != nil {
+			return nil, err
+		}
+		if msg == nil {
+			return nil, errDropped
+		}
+	}
+	return msg, nil
+}
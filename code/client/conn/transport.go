@@ -0,0 +1,103 @@
+package conn
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lwch/natpass/code/network"
+)
+
+func (conn *Conn) dialTCP(ctx context.Context, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	if conn.cfg.TCPFastOpen {
+		dialer.Control = tfoControl
+	}
+	dialAddr, err := conn.resolve(ctx, addr)
+	if err != nil {
+		conn.logError("resolve: %v", err)
+		return nil, &ConnectError{Stage: ErrDial, Addr: addr, Err: err}
+	}
+	dial, err := dialer.DialContext(ctx, "tcp", dialAddr)
+	if err != nil {
+		conn.logError("dial: %v", err)
+		return nil, &ConnectError{Stage: ErrDial, Addr: addr, Err: err}
+	}
+	conn.raw.Store(dial)
+	conn.setSockBuf(dial)
+	return conn.handshakeTLS(ctx, dial, addr)
+}
+
+// handshakeTLS start tls on dialed connection if ssl is enabled
+func (conn *Conn) handshakeTLS(ctx context.Context, dial net.Conn, addr string) (net.Conn, error) {
+	if !conn.cfg.UseSSL {
+		conn.setTLSState(nil)
+		return dial, nil
+	}
+	tc := tls.Client(dial, conn.tlsConfig(addr))
+	deadline, _ := ctx.Deadline()
+	dial.SetDeadline(deadline)
+	err := tc.Handshake()
+	if err != nil {
+		dial.Close()
+		conn.logError("tls handshake: %v", err)
+		return nil, newConnectError(ErrTLS, addr, err)
+	}
+	dial.SetDeadline(time.Time{})
+	state := tc.ConnectionState()
+	conn.setTLSState(&state)
+	return tc, nil
+}
+
+func (conn *Conn) dialWebSocket(ctx context.Context, addr string) (net.Conn, error) {
+	u := url.URL{
+		Scheme: "ws",
+		Host:   addr,
+		Path:   conn.cfg.WSPath,
+	}
+	dialer := *websocket.DefaultDialer
+	dialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		addr, err := conn.resolve(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		var d net.Dialer
+		c, err := d.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		conn.setSockBuf(c)
+		return c, nil
+	}
+	if conn.cfg.UseSSL {
+		u.Scheme = "wss"
+		dialer.TLSClientConfig = conn.tlsConfig(addr)
+	}
+	ws, rep, err := dialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		conn.logError("dial websocket %s: %v", u.String(), err)
+		return nil, newConnectError(websocketStage(err), addr, err)
+	}
+	conn.setTLSState(rep.TLS)
+	return network.NewWebSocketConn(ws), nil
+}
+
+// websocketStage get stage of websocket dial error, the tls handshake is done in dial
+func websocketStage(err error) error {
+	var recordErr tls.RecordHeaderError
+	var certErr x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &recordErr) ||
+		errors.As(err, &certErr) ||
+		errors.As(err, &hostErr) ||
+		errors.As(err, &invalidErr) {
+		return ErrTLS
+	}
+	return ErrDial
+}
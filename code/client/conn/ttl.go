@@ -0,0 +1,47 @@
+package conn
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// SendWithTTL send message like Send, the message is dropped if it is not
+// written in ttl, used by real-time links to discard stale frames under backlog
+func (conn *Conn) SendWithTTL(msg *network.Msg, ttl time.Duration) error {
+	if other := conn.getMigrated(); other != nil {
+		return other.SendWithTTL(msg, ttl)
+	}
+	conn.lockTTL.Lock()
+	conn.ttls[msg] = conn.getClock().Now().Add(ttl)
+	conn.lockTTL.Unlock()
+	err := conn.Send(msg)
+	if err != nil {
+		conn.lockTTL.Lock()
+		delete(conn.ttls, msg)
+		conn.lockTTL.Unlock()
+	}
+	return err
+}
+
+// StaleCount get count of messages dropped by ttl
+func (conn *Conn) StaleCount() uint64 {
+	return atomic.LoadUint64(&conn.staleCount)
+}
+
+// stale check the message is expired, the ttl of message is removed
+func (conn *Conn) stale(msg *network.Msg) bool {
+	conn.lockTTL.Lock()
+	deadline, ok := conn.ttls[msg]
+	if ok {
+		delete(conn.ttls, msg)
+	}
+	conn.lockTTL.Unlock()
+	if !ok || conn.getClock().Now().Before(deadline) {
+		return false
+	}
+	atomic.AddUint64(&conn.staleCount, 1)
+	conn.logDebug("drop stale message %s on link %s", msg.GetXType().String(), msg.GetLinkId())
+	return true
+}
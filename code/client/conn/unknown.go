@@ -0,0 +1,73 @@
+package conn
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// OnUnknownLink set callback when message is routed to unknown link channel
+func (conn *Conn) OnUnknownLink(fn func(linkID string, msg *network.Msg)) {
+	conn.Lock()
+	conn.onUnknown = fn
+	conn.Unlock()
+}
+
+// UnknownCount get count of messages routed to unknown link channel
+func (conn *Conn) UnknownCount() uint64 {
+	return atomic.LoadUint64(&conn.unknownCount)
+}
+
+func (conn *Conn) emitUnknown(linkID string, msg *network.Msg) {
+	atomic.AddUint64(&conn.unknownCount, 1)
+	conn.RLock()
+	fn := conn.onUnknown
+	conn.RUnlock()
+	if fn != nil {
+		fn(linkID, msg)
+	}
+}
+
+// maxOffenders max count of sources recorded by UnknownOffenders
+const maxOffenders = 1024
+
+// unknownAllowed limit unknown link messages of each source per second,
+// the excessive messages are dropped and counted
+func (conn *Conn) unknownAllowed(from string) bool {
+	now := conn.getClock().Now()
+	limit := int(atomic.LoadInt64(&conn.unknownMax))
+	if limit <= 0 {
+		return true
+	}
+	conn.lockUnknown.Lock()
+	defer conn.lockUnknown.Unlock()
+	if now.Sub(conn.unknownStart) >= time.Second {
+		conn.unknownStart = now
+		conn.unknownRate = make(map[string]int)
+	}
+	conn.unknownRate[from]++
+	if conn.unknownRate[from] <= limit {
+		return true
+	}
+	n, ok := conn.offenders[from]
+	if !ok && len(conn.offenders) >= maxOffenders {
+		return false
+	}
+	if n == 0 {
+		conn.logError("too many unknown link messages from %s, dropped", from)
+	}
+	conn.offenders[from] = n + 1
+	return false
+}
+
+// UnknownOffenders get sources and count of unknown link messages dropped by rate limit
+func (conn *Conn) UnknownOffenders() map[string]uint64 {
+	conn.lockUnknown.Lock()
+	defer conn.lockUnknown.Unlock()
+	ret := make(map[string]uint64, len(conn.offenders))
+	for from, n := range conn.offenders {
+		ret[from] = n
+	}
+	return ret
+}
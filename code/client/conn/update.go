@@ -0,0 +1,37 @@
+package conn
+
+import (
+	"reflect"
+	"sync/atomic"
+
+	"github.com/lwch/natpass/code/client/global"
+)
+
+// UpdateConfig apply safe changes of configure on the fly: servers, max_links and unknown_rate,
+// the new servers are used by next reconnect. the configure of connection is not modified,
+// returns names of other changed fields which need restart to take effect
+func (conn *Conn) UpdateConfig(next *global.Configure) []string {
+	if !reflect.DeepEqual(conn.scores.list(), next.Servers) {
+		conn.logInfo("update servers: %v", next.Servers)
+		conn.scores.update(next.Servers)
+	}
+	atomic.StoreInt64(&conn.maxLinks, int64(next.MaxLinks))
+	atomic.StoreInt64(&conn.unknownMax, int64(next.UnknownRate))
+	var restart []string
+	check := func(name string, a, b interface{}) {
+		if !reflect.DeepEqual(a, b) {
+			restart = append(restart, name)
+		}
+	}
+	check("transport", conn.cfg.Transport, next.Transport)
+	check("ssl", conn.cfg.UseSSL, next.UseSSL)
+	check("link.codec", conn.cfg.Codec, next.Codec)
+	check("link.control_conn", conn.cfg.ControlConn, next.ControlConn)
+	check("link.read_timeout", conn.cfg.ReadTimeout, next.ReadTimeout)
+	check("link.write_timeout", conn.cfg.WriteTimeout, next.WriteTimeout)
+	check("rules", conn.cfg.Rules, next.Rules)
+	if len(restart) > 0 {
+		conn.logInfo("configure changed but need restart: %v", restart)
+	}
+	return restart
+}
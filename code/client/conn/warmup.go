@@ -0,0 +1,32 @@
+package conn
+
+import (
+	"github.com/lwch/natpass/code/network"
+)
+
+// SetWarmup set probe called after handshake of data connection, the probe can write
+// and read messages on the new connection directly, e.g. send a message with ReqId
+// and wait for the ack to measure rtt. the connection attempt fails and reconnects
+// if it returns error, nil to disable
+func (conn *Conn) SetWarmup(fn func(cn *network.Conn) error) {
+	conn.Lock()
+	conn.warmup = fn
+	conn.Unlock()
+}
+
+// runWarmup run warmup probe on new connection, the connection is closed if failed
+func (conn *Conn) runWarmup(server string, cn *network.Conn) error {
+	conn.RLock()
+	fn := conn.warmup
+	conn.RUnlock()
+	if fn == nil {
+		return nil
+	}
+	err := fn(cn)
+	if err != nil {
+		cn.Close()
+		conn.logError("warmup %s: %v", server, err)
+		return &ConnectError{Stage: ErrWarmup, Addr: server, Err: err}
+	}
+	return nil
+}
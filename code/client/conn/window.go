@@ -0,0 +1,102 @@
+package conn
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/lwch/natpass/code/client/global"
+)
+
+// window flow control of link, counts messages queued and not written yet
+type window struct {
+	size     int
+	inflight int
+	wait     chan struct{} // closed when a message is dequeued
+}
+
+// SetLinkWindow limit count of messages of link in write queue, 0 to disable.
+// when the window is full Send waits until a message of link is written and returns
+// ErrWindowFull after write timeout, or returns ErrWindowFull immediately in error
+// write_full policy. messages of WriteBatch are counted but not limited
+func (conn *Conn) SetLinkWindow(id string, size int) {
+	conn.lockWindow.Lock()
+	defer conn.lockWindow.Unlock()
+	w := conn.windows[id]
+	if size <= 0 {
+		if w != nil {
+			close(w.wait)
+			delete(conn.windows, id)
+			atomic.AddInt32(&conn.windowCount, -1)
+		}
+		return
+	}
+	if w == nil {
+		w = &window{wait: make(chan struct{})}
+		conn.windows[id] = w
+		atomic.AddInt32(&conn.windowCount, 1)
+	}
+	w.size = size
+}
+
+// LinkInflight get count of messages of link in write queue, -1 if window is not set
+func (conn *Conn) LinkInflight(id string) int {
+	conn.lockWindow.Lock()
+	defer conn.lockWindow.Unlock()
+	w := conn.windows[id]
+	if w == nil {
+		return -1
+	}
+	return w.inflight
+}
+
+// waitWindow wait for window of link is not full
+func (conn *Conn) waitWindow(id string) error {
+	if atomic.LoadInt32(&conn.windowCount) == 0 {
+		return nil
+	}
+	var timeout <-chan time.Time
+	for {
+		conn.lockWindow.Lock()
+		w := conn.windows[id]
+		if w == nil || w.inflight < w.size {
+			conn.lockWindow.Unlock()
+			return nil
+		}
+		ch := w.wait
+		conn.lockWindow.Unlock()
+		if conn.cfg.WriteFullPolicy == global.WriteFullError {
+			return ErrWindowFull
+		}
+		if timeout == nil {
+			timeout = time.After(conn.cfg.WriteTimeout)
+		}
+		select {
+		case <-ch:
+		case <-timeout:
+			return ErrWindowFull
+		case <-conn.ctx.Done():
+			return ErrClosed
+		}
+	}
+}
+
+// moveWindow count message of link enqueued or dequeued
+func (conn *Conn) moveWindow(id string, n int) {
+	if atomic.LoadInt32(&conn.windowCount) == 0 {
+		return
+	}
+	conn.lockWindow.Lock()
+	defer conn.lockWindow.Unlock()
+	w := conn.windows[id]
+	if w == nil {
+		return
+	}
+	w.inflight += n
+	if w.inflight < 0 {
+		w.inflight = 0
+	}
+	if n < 0 {
+		close(w.wait)
+		w.wait = make(chan struct{})
+	}
+}
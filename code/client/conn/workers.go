@@ -0,0 +1,49 @@
+package conn
+
+import (
+	"hash/fnv"
+
+	"github.com/lwch/natpass/code/network"
+	"github.com/lwch/natpass/code/utils"
+)
+
+// workerQueueSize messages queued of each read worker
+const workerQueueSize = 1024
+
+// startWorkers start read_workers goroutines routing messages read by loopRead,
+// messages are routed by loopRead itself if read_workers is 0
+func (conn *Conn) startWorkers() {
+	for i := 0; i < conn.cfg.ReadWorkers; i++ {
+		ch := make(chan *network.Msg, workerQueueSize)
+		conn.workers = append(conn.workers, ch)
+		go conn.runWorker(ch)
+	}
+}
+
+func (conn *Conn) runWorker(ch chan *network.Msg) {
+	defer utils.Recover("read worker")
+	for {
+		select {
+		case msg := <-ch:
+			conn.handle(msg)
+		case <-conn.ctx.Done():
+			return
+		}
+	}
+}
+
+// dispatch route message by worker chosen by link id, so messages of one link
+// are handled by the same worker in order of read
+func (conn *Conn) dispatch(msg *network.Msg) {
+	if len(conn.workers) == 0 {
+		conn.handle(msg)
+		return
+	}
+	h := fnv.New32a()
+	h.Write([]byte(msg.GetLinkId()))
+	ch := conn.workers[h.Sum32()%uint32(len(conn.workers))]
+	select {
+	case ch <- msg:
+	case <-conn.ctx.Done():
+	}
+}
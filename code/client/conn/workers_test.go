@@ -0,0 +1,63 @@
+package conn
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/lwch/natpass/code/client/global"
+	"github.com/lwch/natpass/code/network"
+)
+
+// benchLinks number of links messages are spread over
+const benchLinks = 64
+
+func BenchmarkReadWorkers(b *testing.B) {
+	for _, n := range []int{0, 1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", n), func(b *testing.B) {
+			benchmarkReadWorkers(b, n)
+		})
+	}
+}
+
+func benchmarkReadWorkers(b *testing.B, workers int) {
+	conn := newTestConn(b, func(cfg *global.Configure) {
+		cfg.ReadWorkers = workers
+	})
+	conn.startWorkers()
+	msgs := make([]*network.Msg, benchLinks)
+	var wg sync.WaitGroup
+	for i := range msgs {
+		id := fmt.Sprintf("link-%d", i)
+		if err := conn.AddLink(id); err != nil {
+			b.Fatal(err)
+		}
+		ch := conn.ChanRead(id)
+		go func() {
+			for {
+				select {
+				case <-ch:
+					wg.Done()
+				case <-conn.ctx.Done():
+					return
+				}
+			}
+		}()
+		var msg network.Msg
+		msg.From = "peer"
+		msg.To = "me"
+		msg.XType = network.Msg_forward
+		msg.LinkId = id
+		msg.Payload = &network.Msg_XData{
+			XData: &network.Data{Data: make([]byte, 1024)},
+		}
+		msg.Checksum = checksum(&msg)
+		msgs[i] = &msg
+	}
+	wg.Add(b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		conn.dispatch(msgs[i%benchLinks])
+	}
+	wg.Wait()
+}
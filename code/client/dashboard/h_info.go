@@ -3,17 +3,34 @@ package dashboard
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
+	"github.com/lwch/natpass/code/client/conn"
 	"github.com/lwch/natpass/code/client/rule"
+	"github.com/lwch/natpass/code/network"
 )
 
 // Info information data
 func (db *Dashboard) Info(w http.ResponseWriter, r *http.Request) {
 	var ret struct {
-		Rules        int `json:"rules"`
-		VirtualLinks int `json:"virtual_links"`
-		Session      int `json:"sessions"`
+		Labels       map[string]string         `json:"labels"`
+		Rules        int                       `json:"rules"`
+		VirtualLinks int                       `json:"virtual_links"`
+		Session      int                       `json:"sessions"`
+		Breaker      string                    `json:"breaker"`
+		Servers      map[string]float64        `json:"servers"`
+		Encode       network.HistogramSnapshot `json:"encode_latency"`
+		Decode       network.HistogramSnapshot `json:"decode_latency"`
+		RTT          []conn.RTTSample          `json:"rtt"`
+		Buffered     int                       `json:"buffered"`
+		Lameduck     bool                      `json:"lameduck"`
+		LinkRate     float64                   `json:"link_rate"`
+		Connected    bool                      `json:"connected"`
+		Paused       bool                      `json:"paused"`
+		LastRead     time.Time                 `json:"last_read"`
+		LastWrite    time.Time                 `json:"last_write"`
 	}
+	ret.Labels = db.conn.Labels()
 	ret.Rules = len(db.cfg.Rules)
 	db.mgr.Range(func(t rule.Rule) {
 		n := len(t.GetLinks())
@@ -23,6 +40,18 @@ func (db *Dashboard) Info(w http.ResponseWriter, r *http.Request) {
 			ret.Session += n
 		}
 	})
+	ret.Breaker = db.conn.BreakerState().String()
+	ret.Servers = db.conn.ServerScores()
+	ret.Encode = db.conn.EncodeLatency()
+	ret.Decode = db.conn.DecodeLatency()
+	ret.RTT = db.conn.RTTHistory()
+	ret.Buffered = db.conn.BufferedBytes()
+	ret.Lameduck = db.conn.IsLameduck()
+	ret.LinkRate = db.conn.LinkRate()
+	ret.Connected = db.conn.IsConnected()
+	ret.Paused = db.conn.IsPaused()
+	ret.LastRead = db.conn.LastReadAt()
+	ret.LastWrite = db.conn.LastWriteAt()
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(ret)
 }
@@ -3,15 +3,65 @@ package global
 import (
 	"crypto/md5"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"regexp"
 	"time"
 
+	"github.com/lwch/natpass/code/network"
 	"github.com/lwch/natpass/code/utils"
 	"github.com/lwch/runtime"
 	"github.com/lwch/yaml"
 )
 
+const (
+	// WriteFullBlock block until write timeout when write queue is full
+	WriteFullBlock = "block"
+	// WriteFullError returns error immediately when write queue is full
+	WriteFullError = "error"
+	// WriteFullDropOldest drop the oldest message when write queue is full
+	WriteFullDropOldest = "drop-oldest"
+	// BufferFullBlock wait until buffered messages below max_buffer
+	BufferFullBlock = "block"
+	// BufferFullDrop drop the message when buffered messages exceeded max_buffer
+	BufferFullDrop = "drop"
+	// PauseQueue queue application messages until resumed
+	PauseQueue = "queue"
+	// PauseReject reject application messages while paused
+	PauseReject = "reject"
+	// DLPRedact replace matched data of outbound message by *
+	DLPRedact = "redact"
+	// DLPReject drop outbound message with matched data
+	DLPReject = "reject"
+)
+
+const (
+	// RetryDial retry when dial to server failed
+	RetryDial = "dial"
+	// RetryTLS retry when tls handshake failed
+	RetryTLS = "tls"
+	// RetryHandshake retry when write handshake message failed
+	RetryHandshake = "handshake"
+)
+
+const (
+	// TransportTCP connect to server by tcp
+	TransportTCP = "tcp"
+	// TransportWebSocket connect to server by websocket
+	TransportWebSocket = "websocket"
+)
+
+// ReconnectPolicy backoff of reconnect, the delay of n-th retry is
+// Initial*Multiplier^(n-1) limited by Max, then randomized by Jitter percent
+type ReconnectPolicy struct {
+	Initial     time.Duration
+	Multiplier  float64
+	Max         time.Duration
+	MaxAttempts int // 0 is unlimited
+	Jitter      int // percent of delay, [0, 100]
+}
+
 // Rule rule config
 type Rule struct {
 	Name      string `yaml:"name"`
@@ -20,54 +70,179 @@ type Rule struct {
 	LocalAddr string `yaml:"local_addr"`
 	LocalPort uint16 `yaml:"local_port"`
 	// shell
-	Exec string   `yaml:"exec"`
-	Env  []string `yaml:"env"`
+	Exec     string        `yaml:"exec"`
+	Env      []string      `yaml:"env"`
+	Coalesce time.Duration `yaml:"coalesce"`
 	// vnc
 	Fps uint32 `yaml:"fps"`
 }
 
 // Configure client configure
 type Configure struct {
-	ID               string
-	Server           string
-	UseSSL           bool
-	Enc              [md5.Size]byte
-	Links            int
-	LogDir           string
-	LogSize          utils.Bytes
-	LogRotate        int
-	ReadTimeout      time.Duration
-	WriteTimeout     time.Duration
-	DashboardEnabled bool
-	DashboardListen  string
-	DashboardPort    uint16
-	Rules            []Rule
+	ID                   string
+	Name                 string
+	Labels               map[string]string
+	Server               string
+	Servers              []string
+	UseSSL               bool
+	TCPFastOpen          bool
+	Transport            string
+	WSPath               string
+	DoH                  string
+	RelayChain           []string
+	Enc                  [md5.Size]byte
+	Links                int
+	LogDir               string
+	LogSize              utils.Bytes
+	LogRotate            int
+	MessageLog           bool
+	MessageLogPayload    bool
+	MessageLogSize       utils.Bytes
+	MessageLogRotate     int
+	DLPAction            string
+	DLPPatterns          []string
+	ReadTimeout          time.Duration
+	WriteTimeout         time.Duration
+	IdleTimeout          time.Duration
+	HandshakeTimeout     time.Duration
+	WriteFullPolicy      string
+	KeepalivePayloadSize int
+	KeepaliveMetrics     time.Duration
+	ReadPool             bool
+	PausePolicy          string
+	ReadWorkers          int
+	Loopback             bool
+	Checksum             bool
+	Codec                string
+	HandshakeExt         map[string]string
+	AutoRegister         bool
+	MaxLinks             int
+	ControlConn          bool
+	CompressHandshake    bool
+	RouteByPeer          bool
+	SocketReadBuffer     utils.Bytes
+	SocketWriteBuffer    utils.Bytes
+	MaxMessageSize       utils.Bytes
+	MaxBuffer            utils.Bytes
+	BufferFullPolicy     string
+	HandshakeAck         bool
+	AdaptiveReadTimeout  bool
+	UnknownRate          int
+	LinkRate             float64
+	LinkBurst            int
+	ReconnectThreshold   int
+	ReconnectCooldown    time.Duration
+	ReconnectProbe       time.Duration
+	ReconnectPolicy      ReconnectPolicy
+	RetryOn              []string
+	DashboardEnabled     bool
+	DashboardListen      string
+	DashboardPort        uint16
+	Rules                []Rule
+	RemoteInterval       time.Duration
+	seed                 *rawConf // local configure used to refresh remote configure
+}
+
+// rawConf format of configure file
+type rawConf struct {
+	ID        string            `yaml:"id"`
+	Name      string            `yaml:"name"`
+	Labels    map[string]string `yaml:"labels"`
+	Server    string            `yaml:"server"`
+	Servers   []string          `yaml:"servers"`
+	Secret    string            `yaml:"secret"`
+	SSL       bool              `yaml:"ssl"`
+	TFO       bool              `yaml:"tfo"`
+	Transport string            `yaml:"transport"`
+	WSPath    string            `yaml:"websocket_path"`
+	Ext       map[string]string `yaml:"handshake_ext"`
+	DoH       string            `yaml:"doh"`
+	Relay     []string          `yaml:"relay_chain"`
+	Link      struct {
+		ReadTimeout   time.Duration `yaml:"read_timeout"`
+		WriteTimeout  time.Duration `yaml:"write_timeout"`
+		IdleTimeout   time.Duration `yaml:"idle_timeout"`
+		Handshake     time.Duration `yaml:"handshake_timeout"`
+		WriteFull     string        `yaml:"write_full"`
+		KeepaliveSize int           `yaml:"keepalive_payload_size"`
+		KeepaliveStat time.Duration `yaml:"keepalive_metrics"`
+		ReadPool      bool          `yaml:"read_pool"`
+		Pause         string        `yaml:"pause"`
+		ReadWorkers   int           `yaml:"read_workers"`
+		Loopback      *bool         `yaml:"loopback"`
+		Checksum      bool          `yaml:"checksum"`
+		Codec         string        `yaml:"codec"`
+		AutoRegister  bool          `yaml:"auto_register"`
+		MaxLinks      int           `yaml:"max_links"`
+		ControlConn   bool          `yaml:"control_conn"`
+		Compress      bool          `yaml:"compress_handshake"`
+		RouteByPeer   bool          `yaml:"route_by_peer"`
+		ReadBuffer    utils.Bytes   `yaml:"socket_read_buffer"`
+		WriteBuffer   utils.Bytes   `yaml:"socket_write_buffer"`
+		HandshakeAck  *bool         `yaml:"handshake_ack"`
+		AdaptiveRead  bool          `yaml:"adaptive_read_timeout"`
+		UnknownRate   int           `yaml:"unknown_rate"`
+		LinkRate      float64       `yaml:"link_rate"`
+		LinkBurst     int           `yaml:"link_burst"`
+		MaxBuffer     utils.Bytes   `yaml:"max_buffer"`
+		MaxMessage    utils.Bytes   `yaml:"max_message_size"`
+		BufferFull    string        `yaml:"buffer_full"`
+	} `yaml:"link"`
+	Reconnect struct {
+		Threshold int           `yaml:"threshold"`
+		Cooldown  time.Duration `yaml:"cooldown"`
+		Probe     time.Duration `yaml:"probe"`
+		RetryOn   []string      `yaml:"retry_on"`
+		Initial   time.Duration `yaml:"initial_delay"`
+		Multiply  float64       `yaml:"multiplier"`
+		Max       time.Duration `yaml:"max_delay"`
+		Attempts  int           `yaml:"max_attempts"`
+		Jitter    int           `yaml:"jitter"`
+	} `yaml:"reconnect"`
+	Log struct {
+		Dir    string      `yaml:"dir"`
+		Size   utils.Bytes `yaml:"size"`
+		Rotate int         `yaml:"rotate"`
+	} `yaml:"log"`
+	MessageLog struct {
+		Enabled bool        `yaml:"enabled"`
+		Payload bool        `yaml:"payload"`
+		Size    utils.Bytes `yaml:"size"`
+		Rotate  int         `yaml:"rotate"`
+	} `yaml:"message_log"`
+	DLP struct {
+		Action   string   `yaml:"action"`
+		Patterns []string `yaml:"patterns"`
+	} `yaml:"dlp"`
+	Dashboard struct {
+		Enabled bool   `yaml:"enabled"`
+		Listen  string `yaml:"listen"`
+		Port    uint16 `yaml:"port"`
+	} `yaml:"dashboard"`
+	Remote struct {
+		URL      string        `yaml:"url"`
+		Interval time.Duration `yaml:"interval"`
+	} `yaml:"remote"`
+	Rules []Rule `yaml:"rules"`
 }
 
-// LoadConf load configure file
+// LoadConf load configure file, the configure is fetched from remote.url if set
 func LoadConf(dir string) *Configure {
-	var cfg struct {
-		ID     string `yaml:"id"`
-		Server string `yaml:"server"`
-		Secret string `yaml:"secret"`
-		SSL    bool   `yaml:"ssl"`
-		Link   struct {
-			ReadTimeout  time.Duration `yaml:"read_timeout"`
-			WriteTimeout time.Duration `yaml:"write_timeout"`
-		} `yaml:"link"`
-		Log struct {
-			Dir    string      `yaml:"dir"`
-			Size   utils.Bytes `yaml:"size"`
-			Rotate int         `yaml:"rotate"`
-		} `yaml:"log"`
-		Dashboard struct {
-			Enabled bool   `yaml:"enabled"`
-			Listen  string `yaml:"listen"`
-			Port    uint16 `yaml:"port"`
-		} `yaml:"dashboard"`
-		Rules []Rule `yaml:"rules"`
-	}
-	runtime.Assert(yaml.Decode(dir, &cfg))
+	var local rawConf
+	runtime.Assert(yaml.Decode(dir, &local))
+	cfg := local
+	if len(local.Remote.URL) > 0 {
+		var err error
+		cfg, err = local.fetch()
+		runtime.Assert(err)
+	}
+	ret := cfg.build()
+	ret.seed = &local
+	return ret
+}
+
+// build normalize configure, panics on invalid value
+func (cfg rawConf) build() *Configure {
 	for i, t := range cfg.Rules {
 		switch t.Type {
 		case "shell", "vnc", "bench":
@@ -76,30 +251,216 @@ func LoadConf(dir string) *Configure {
 		}
 		cfg.Rules[i] = t
 	}
+	if len(cfg.Server) > 0 {
+		cfg.Servers = append([]string{cfg.Server}, cfg.Servers...)
+	}
+	if len(cfg.Servers) == 0 {
+		panic("missing server")
+	}
 	if cfg.Link.ReadTimeout <= 0 {
 		cfg.Link.ReadTimeout = 5 * time.Second
 	}
 	if cfg.Link.WriteTimeout <= 0 {
 		cfg.Link.WriteTimeout = 5 * time.Second
 	}
+	if cfg.Link.Handshake <= 0 {
+		cfg.Link.Handshake = 10 * time.Second
+	}
+	switch cfg.Transport {
+	case "":
+		cfg.Transport = TransportTCP
+	case TransportTCP, TransportWebSocket:
+	default:
+		panic(fmt.Sprintf("unsupported transport: %s", cfg.Transport))
+	}
+	if len(cfg.WSPath) == 0 {
+		cfg.WSPath = "/natpass"
+	}
+	if len(cfg.Relay) > 0 && cfg.Transport != TransportTCP {
+		panic("relay_chain only supports tcp transport")
+	}
+	if !network.ValidHandshakeExt(cfg.Ext) {
+		panic(fmt.Sprintf("too large handshake_ext, max %d items, key %d bytes, value %d bytes",
+			network.MaxHandshakeExt, network.MaxHandshakeExtKey, network.MaxHandshakeExtValue))
+	}
+	switch cfg.Link.Codec {
+	case "":
+		cfg.Link.Codec = "protobuf"
+	case "protobuf", "json":
+	default:
+		panic(fmt.Sprintf("unsupported codec: %s", cfg.Link.Codec))
+	}
+	switch cfg.Link.WriteFull {
+	case "":
+		cfg.Link.WriteFull = WriteFullBlock
+	case WriteFullBlock, WriteFullError, WriteFullDropOldest:
+	default:
+		panic(fmt.Sprintf("unsupported write_full policy: %s", cfg.Link.WriteFull))
+	}
+	switch cfg.Link.BufferFull {
+	case "":
+		cfg.Link.BufferFull = BufferFullBlock
+	case BufferFullBlock, BufferFullDrop:
+	default:
+		panic(fmt.Sprintf("unsupported buffer_full policy: %s", cfg.Link.BufferFull))
+	}
+	switch cfg.Link.Pause {
+	case "":
+		cfg.Link.Pause = PauseQueue
+	case PauseQueue, PauseReject:
+	default:
+		panic(fmt.Sprintf("unsupported pause policy: %s", cfg.Link.Pause))
+	}
+	if cfg.Link.KeepaliveSize < 0 ||
+		cfg.Link.KeepaliveSize > 60000 {
+		panic(fmt.Sprintf("invalid keepalive_payload_size: %d", cfg.Link.KeepaliveSize))
+	}
+	if cfg.Link.MaxMessage.Bytes() > network.MaxMessageSize {
+		panic(fmt.Sprintf("too large max_message_size, max %d", network.MaxMessageSize))
+	}
+	if cfg.Link.HandshakeAck == nil {
+		ack := true
+		cfg.Link.HandshakeAck = &ack
+	}
+	if cfg.Link.Loopback == nil {
+		loopback := true
+		cfg.Link.Loopback = &loopback
+	}
+	if cfg.Link.UnknownRate == 0 {
+		cfg.Link.UnknownRate = 100
+	}
+	if cfg.Link.MaxLinks <= 0 {
+		cfg.Link.MaxLinks = 1024
+	}
+	if cfg.Link.LinkRate < 0 {
+		panic(fmt.Sprintf("invalid link_rate: %v", cfg.Link.LinkRate))
+	}
+	if cfg.Link.LinkRate > 0 && cfg.Link.LinkBurst <= 0 {
+		cfg.Link.LinkBurst = int(math.Ceil(cfg.Link.LinkRate))
+	}
+	if cfg.Reconnect.Threshold <= 0 {
+		cfg.Reconnect.Threshold = 5
+	}
+	if cfg.Reconnect.Cooldown <= 0 {
+		cfg.Reconnect.Cooldown = 30 * time.Second
+	}
+	if cfg.Reconnect.Probe <= 0 {
+		cfg.Reconnect.Probe = 30 * time.Second
+	}
+	if cfg.Reconnect.Initial <= 0 {
+		cfg.Reconnect.Initial = time.Second
+	}
+	if cfg.Reconnect.Multiply == 0 {
+		cfg.Reconnect.Multiply = 2
+	}
+	if cfg.Reconnect.Max <= 0 {
+		cfg.Reconnect.Max = 30 * time.Second
+	}
+	policy := ReconnectPolicy{
+		Initial:     cfg.Reconnect.Initial,
+		Multiplier:  cfg.Reconnect.Multiply,
+		Max:         cfg.Reconnect.Max,
+		MaxAttempts: cfg.Reconnect.Attempts,
+		Jitter:      cfg.Reconnect.Jitter,
+	}
+	if err := policy.validate(); err != nil {
+		panic(fmt.Sprintf("invalid reconnect policy: %v", err))
+	}
+	if len(cfg.Reconnect.RetryOn) == 0 {
+		cfg.Reconnect.RetryOn = []string{RetryDial, RetryTLS, RetryHandshake}
+	}
+	for _, class := range cfg.Reconnect.RetryOn {
+		switch class {
+		case RetryDial, RetryTLS, RetryHandshake:
+		default:
+			panic(fmt.Sprintf("unsupported retry_on: %s", class))
+		}
+	}
+	switch cfg.DLP.Action {
+	case "":
+		cfg.DLP.Action = DLPReject
+	case DLPRedact, DLPReject:
+	default:
+		panic(fmt.Sprintf("unsupported dlp action: %s", cfg.DLP.Action))
+	}
+	for _, pattern := range cfg.DLP.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			panic(fmt.Sprintf("invalid dlp pattern %q: %v", pattern, err))
+		}
+	}
+	if cfg.MessageLog.Size.Bytes() == 0 {
+		cfg.MessageLog.Size = cfg.Log.Size
+	}
+	if cfg.MessageLog.Rotate <= 0 {
+		cfg.MessageLog.Rotate = cfg.Log.Rotate
+	}
 	if !filepath.IsAbs(cfg.Log.Dir) {
 		dir, err := os.Executable()
 		runtime.Assert(err)
 		cfg.Log.Dir = filepath.Join(filepath.Dir(dir), cfg.Log.Dir)
 	}
-	return &Configure{
-		ID:               cfg.ID,
-		Server:           cfg.Server,
-		UseSSL:           cfg.SSL,
-		Enc:              md5.Sum([]byte(cfg.Secret)),
-		ReadTimeout:      cfg.Link.ReadTimeout,
-		WriteTimeout:     cfg.Link.WriteTimeout,
-		LogDir:           cfg.Log.Dir,
-		LogSize:          cfg.Log.Size,
-		LogRotate:        cfg.Log.Rotate,
-		DashboardEnabled: cfg.Dashboard.Enabled,
-		DashboardListen:  cfg.Dashboard.Listen,
-		DashboardPort:    cfg.Dashboard.Port,
-		Rules:            cfg.Rules,
+	ret := &Configure{
+		ID:                   cfg.ID,
+		Name:                 cfg.Name,
+		Labels:               cfg.Labels,
+		Server:               cfg.Servers[0],
+		Servers:              cfg.Servers,
+		UseSSL:               cfg.SSL,
+		TCPFastOpen:          cfg.TFO,
+		Transport:            cfg.Transport,
+		WSPath:               cfg.WSPath,
+		DoH:                  cfg.DoH,
+		RelayChain:           cfg.Relay,
+		Enc:                  md5.Sum([]byte(cfg.Secret)),
+		ReadTimeout:          cfg.Link.ReadTimeout,
+		WriteTimeout:         cfg.Link.WriteTimeout,
+		IdleTimeout:          cfg.Link.IdleTimeout,
+		HandshakeTimeout:     cfg.Link.Handshake,
+		WriteFullPolicy:      cfg.Link.WriteFull,
+		KeepalivePayloadSize: cfg.Link.KeepaliveSize,
+		KeepaliveMetrics:     cfg.Link.KeepaliveStat,
+		ReadPool:             cfg.Link.ReadPool,
+		PausePolicy:          cfg.Link.Pause,
+		ReadWorkers:          cfg.Link.ReadWorkers,
+		Loopback:             *cfg.Link.Loopback,
+		Checksum:             cfg.Link.Checksum,
+		Codec:                cfg.Link.Codec,
+		HandshakeExt:         cfg.Ext,
+		AutoRegister:         cfg.Link.AutoRegister,
+		MaxLinks:             cfg.Link.MaxLinks,
+		ControlConn:          cfg.Link.ControlConn,
+		CompressHandshake:    cfg.Link.Compress,
+		RouteByPeer:          cfg.Link.RouteByPeer,
+		SocketReadBuffer:     cfg.Link.ReadBuffer,
+		SocketWriteBuffer:    cfg.Link.WriteBuffer,
+		MaxBuffer:            cfg.Link.MaxBuffer,
+		MaxMessageSize:       cfg.Link.MaxMessage,
+		BufferFullPolicy:     cfg.Link.BufferFull,
+		HandshakeAck:         *cfg.Link.HandshakeAck,
+		AdaptiveReadTimeout:  cfg.Link.AdaptiveRead,
+		UnknownRate:          cfg.Link.UnknownRate,
+		LinkRate:             cfg.Link.LinkRate,
+		LinkBurst:            cfg.Link.LinkBurst,
+		ReconnectThreshold:   cfg.Reconnect.Threshold,
+		ReconnectCooldown:    cfg.Reconnect.Cooldown,
+		ReconnectProbe:       cfg.Reconnect.Probe,
+		ReconnectPolicy:      policy,
+		RetryOn:              cfg.Reconnect.RetryOn,
+		LogDir:               cfg.Log.Dir,
+		LogSize:              cfg.Log.Size,
+		LogRotate:            cfg.Log.Rotate,
+		MessageLog:           cfg.MessageLog.Enabled,
+		MessageLogPayload:    cfg.MessageLog.Payload,
+		DLPAction:            cfg.DLP.Action,
+		DLPPatterns:          cfg.DLP.Patterns,
+		MessageLogSize:       cfg.MessageLog.Size,
+		MessageLogRotate:     cfg.MessageLog.Rotate,
+		DashboardEnabled:     cfg.Dashboard.Enabled,
+		DashboardListen:      cfg.Dashboard.Listen,
+		DashboardPort:        cfg.Dashboard.Port,
+		Rules:                cfg.Rules,
+		RemoteInterval:       cfg.Remote.Interval,
 	}
+	ret.loadEnv()
+	return ret
 }
@@ -0,0 +1,56 @@
+package global
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// effectiveID get client id overridden by NATPASS_ID
+func (cfg rawConf) effectiveID() string {
+	if id, ok := os.LookupEnv("NATPASS_ID"); ok && len(id) > 0 {
+		return id
+	}
+	return cfg.ID
+}
+
+// loadEnv override configure from environment variables, priority:
+// NATPASS_ENC > NATPASS_SECRET > configure file
+func (cfg *Configure) loadEnv() {
+	if id, ok := os.LookupEnv("NATPASS_ID"); ok {
+		if len(id) == 0 {
+			panic("empty NATPASS_ID")
+		}
+		cfg.ID = id
+	}
+	if server, ok := os.LookupEnv("NATPASS_SERVER"); ok {
+		if len(server) == 0 {
+			panic("empty NATPASS_SERVER")
+		}
+		cfg.Server = server
+		cfg.Servers = []string{server}
+	}
+	if ssl, ok := os.LookupEnv("NATPASS_SSL"); ok {
+		b, err := strconv.ParseBool(ssl)
+		if err != nil {
+			panic(fmt.Sprintf("invalid NATPASS_SSL: %v", err))
+		}
+		cfg.UseSSL = b
+	}
+	if secret, ok := os.LookupEnv("NATPASS_SECRET"); ok {
+		cfg.Enc = md5.Sum([]byte(secret))
+	}
+	if enc, ok := os.LookupEnv("NATPASS_ENC"); ok {
+		data, err := hex.DecodeString(enc)
+		if err != nil {
+			panic(fmt.Sprintf("invalid NATPASS_ENC: %v", err))
+		}
+		if len(data) != md5.Size {
+			panic(fmt.Sprintf("invalid NATPASS_ENC: expect %d bytes, got %d",
+				md5.Size, len(data)))
+		}
+		copy(cfg.Enc[:], data)
+	}
+}
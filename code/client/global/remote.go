@@ -0,0 +1,88 @@
+package global
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/lwch/yaml"
+)
+
+// maxRemoteConf max size of remote configure
+const maxRemoteConf = 1 << 20
+
+// fetch get configure from remote url, the id, secret, log and remote settings
+// are always seeded from local configure
+func (local rawConf) fetch() (rawConf, error) {
+	u, err := url.Parse(local.Remote.URL)
+	if err != nil {
+		return rawConf{}, err
+	}
+	query := u.Query()
+	query.Set("id", local.effectiveID())
+	u.RawQuery = query.Encode()
+	cli := http.Client{Timeout: 30 * time.Second}
+	rep, err := cli.Get(u.String())
+	if err != nil {
+		return rawConf{}, err
+	}
+	defer rep.Body.Close()
+	if rep.StatusCode != http.StatusOK {
+		return rawConf{}, fmt.Errorf("fetch remote configure: http status %d", rep.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(rep.Body, maxRemoteConf))
+	if err != nil {
+		return rawConf{}, err
+	}
+	ret, err := decodeRemote(data)
+	if err != nil {
+		return rawConf{}, err
+	}
+	ret.ID = local.ID
+	ret.Secret = local.Secret
+	ret.Log = local.Log
+	ret.Remote = local.Remote
+	return ret, nil
+}
+
+// decodeRemote decode remote configure the same way as configure file
+func decodeRemote(data []byte) (rawConf, error) {
+	f, err := os.CreateTemp("", "natpass-remote-*.yaml")
+	if err != nil {
+		return rawConf{}, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	f.Close()
+	if err != nil {
+		return rawConf{}, err
+	}
+	var ret rawConf
+	err = yaml.Decode(f.Name(), &ret)
+	if err != nil {
+		return rawConf{}, err
+	}
+	return ret, nil
+}
+
+// Refresh fetch remote configure again, returns error if remote configure is not set or invalid
+func (cfg *Configure) Refresh() (ret *Configure, err error) {
+	if cfg.seed == nil || len(cfg.seed.Remote.URL) == 0 {
+		return nil, fmt.Errorf("remote configure is not set")
+	}
+	raw, err := cfg.seed.fetch()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			ret, err = nil, fmt.Errorf("invalid remote configure: %v", e)
+		}
+	}()
+	ret = raw.build()
+	ret.seed = cfg.seed
+	return ret, nil
+}
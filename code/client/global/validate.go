@@ -0,0 +1,217 @@
+package global
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lwch/natpass/code/network"
+)
+
+// FieldError invalid value of configure field
+type FieldError struct {
+	Field string
+	Msg   string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Msg
+}
+
+// ValidateErrors all errors found by Validate
+type ValidateErrors []FieldError
+
+func (errs ValidateErrors) Error() string {
+	strs := make([]string, len(errs))
+	for i, err := range errs {
+		strs[i] = err.Error()
+	}
+	return strings.Join(strs, "; ")
+}
+
+// Validate check all fields without connecting to server, returns ValidateErrors if any
+func (cfg *Configure) Validate() error {
+	var errs ValidateErrors
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Msg: fmt.Sprintf(format, args...)})
+	}
+	if len(cfg.ID) == 0 {
+		add("id", "empty")
+	}
+	if len(cfg.Servers) == 0 {
+		add("server", "empty")
+	}
+	for i, server := range cfg.Servers {
+		if err := validServer(server); err != nil {
+			add(fmt.Sprintf("servers[%d]", i), "%s: %v", server, err)
+		}
+	}
+	switch cfg.Transport {
+	case TransportTCP, TransportWebSocket:
+	default:
+		add("transport", "unsupported %q", cfg.Transport)
+	}
+	if cfg.Transport == TransportWebSocket && !strings.HasPrefix(cfg.WSPath, "/") {
+		add("websocket_path", "must start with /")
+	}
+	for i, relay := range cfg.RelayChain {
+		if err := validServer(relay); err != nil {
+			add(fmt.Sprintf("relay_chain[%d]", i), "%s: %v", relay, err)
+		}
+	}
+	if len(cfg.RelayChain) > 0 && cfg.Transport != TransportTCP {
+		add("relay_chain", "only supports tcp transport")
+	}
+	if len(cfg.DoH) > 0 && !strings.HasPrefix(cfg.DoH, "https://") {
+		add("doh", "must be https url")
+	}
+	if cfg.ReadTimeout <= 0 {
+		add("link.read_timeout", "must be positive")
+	}
+	if cfg.WriteTimeout <= 0 {
+		add("link.write_timeout", "must be positive")
+	}
+	if cfg.IdleTimeout < 0 {
+		add("link.idle_timeout", "must not be negative")
+	}
+	if cfg.ReadWorkers < 0 {
+		add("link.read_workers", "must not be negative")
+	}
+	if cfg.KeepaliveMetrics < 0 {
+		add("link.keepalive_metrics", "must not be negative")
+	}
+	if cfg.HandshakeTimeout <= 0 {
+		add("link.handshake_timeout", "must be positive")
+	}
+	switch cfg.DLPAction {
+	case DLPRedact, DLPReject:
+	default:
+		add("dlp.action", "unsupported %q", cfg.DLPAction)
+	}
+	for i, pattern := range cfg.DLPPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			add(fmt.Sprintf("dlp.patterns[%d]", i), "%v", err)
+		}
+	}
+	switch cfg.BufferFullPolicy {
+	case BufferFullBlock, BufferFullDrop:
+	default:
+		add("link.buffer_full", "unsupported %q", cfg.BufferFullPolicy)
+	}
+	switch cfg.PausePolicy {
+	case PauseQueue, PauseReject:
+	default:
+		add("link.pause", "unsupported %q", cfg.PausePolicy)
+	}
+	switch cfg.WriteFullPolicy {
+	case WriteFullBlock, WriteFullError, WriteFullDropOldest:
+	default:
+		add("link.write_full", "unsupported %q", cfg.WriteFullPolicy)
+	}
+	if network.GetCodec(cfg.Codec) == nil {
+		add("link.codec", "unsupported %q", cfg.Codec)
+	}
+	if cfg.KeepalivePayloadSize < 0 || cfg.KeepalivePayloadSize > 60000 {
+		add("link.keepalive_payload_size", "out of range [0, 60000]")
+	}
+	if cfg.MaxMessageSize.Bytes() > network.MaxMessageSize {
+		add("link.max_message_size", "must not be larger than %d", network.MaxMessageSize)
+	}
+	if cfg.MaxLinks <= 0 {
+		add("link.max_links", "must be positive")
+	}
+	if cfg.LinkRate < 0 {
+		add("link.link_rate", "must not be negative")
+	}
+	if !network.ValidHandshakeExt(cfg.HandshakeExt) {
+		add("handshake_ext", "max %d items, key %d bytes, value %d bytes",
+			network.MaxHandshakeExt, network.MaxHandshakeExtKey, network.MaxHandshakeExtValue)
+	}
+	if cfg.ReconnectThreshold <= 0 {
+		add("reconnect.threshold", "must be positive")
+	}
+	if cfg.ReconnectCooldown < 0 {
+		add("reconnect.cooldown", "must not be negative")
+	}
+	if cfg.ReconnectProbe < 0 {
+		add("reconnect.probe", "must not be negative")
+	}
+	if err := cfg.ReconnectPolicy.validate(); err != nil {
+		add("reconnect", "%v", err)
+	}
+	for i, class := range cfg.RetryOn {
+		switch class {
+		case RetryDial, RetryTLS, RetryHandshake:
+		default:
+			add(fmt.Sprintf("reconnect.retry_on[%d]", i), "unsupported %q", class)
+		}
+	}
+	if cfg.LogRotate < 0 {
+		add("log.rotate", "must not be negative")
+	}
+	names := make(map[string]bool, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		field := fmt.Sprintf("rules[%d]", i)
+		if len(rule.Name) == 0 {
+			add(field+".name", "empty")
+		} else if names[rule.Name] {
+			add(field+".name", "duplicate %q", rule.Name)
+		}
+		names[rule.Name] = true
+		if len(rule.Target) == 0 {
+			add(field+".target", "empty")
+		}
+		switch rule.Type {
+		case "shell", "vnc", "bench":
+		default:
+			add(field+".type", "unsupported %q", rule.Type)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validServer check server address, the port is optional
+func validServer(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		if !strings.Contains(err.Error(), "missing port") &&
+			!strings.Contains(err.Error(), "too many colons") {
+			return err
+		}
+		host = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+		port = "6154"
+	}
+	if len(host) == 0 {
+		return fmt.Errorf("missing host")
+	}
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || n == 0 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
+
+// validate check multiplier >= 1, max delay >= initial delay and jitter in [0, 100]
+func (p ReconnectPolicy) validate() error {
+	if p.Initial <= 0 {
+		return fmt.Errorf("initial_delay must be positive")
+	}
+	if p.Multiplier < 1 {
+		return fmt.Errorf("multiplier must not be less than 1")
+	}
+	if p.Max < p.Initial {
+		return fmt.Errorf("max_delay must not be less than initial_delay")
+	}
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("max_attempts must not be negative")
+	}
+	if p.Jitter < 0 || p.Jitter > 100 {
+		return fmt.Errorf("jitter out of range [0, 100]")
+	}
+	return nil
+}
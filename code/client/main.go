@@ -35,6 +35,7 @@ func main() {
 	user := flag.String("user", "", "service user")
 	conf := flag.String("conf", "", "configure file path")
 	ver := flag.Bool("version", false, "show version info")
+	check := flag.Bool("check", false, "validate configure file and exit")
 	act := flag.String("action", "", "install or uninstall")
 	name := flag.String("name", "", "rule name")
 	vport := flag.Uint("vport", 6155, "vnc worker listen port")
@@ -75,6 +76,16 @@ func main() {
 
 	cfg := global.LoadConf(*conf)
 
+	if *check {
+		err := cfg.Validate()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println("configure is ok")
+		os.Exit(0)
+	}
+
 	if *act == "vnc.worker" {
 		defer utils.Recover("vnc.worker")
 		stdout := true
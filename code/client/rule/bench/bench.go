@@ -47,8 +47,8 @@ func New(cfg global.Rule) *Bench {
 }
 
 // NewLink new link
-func (bench *Bench) NewLink(id, remote string, localConn net.Conn, remoteConn *conn.Conn) rule.Link {
-	return &Link{id: id}
+func (bench *Bench) NewLink(id, remote string, localConn net.Conn, remoteConn *conn.Conn) (rule.Link, error) {
+	return &Link{id: id}, nil
 }
 
 // GetName get bench rule name
@@ -6,18 +6,21 @@ import (
 
 	"github.com/lwch/logging"
 	"github.com/lwch/natpass/code/client/conn"
-	"github.com/lwch/runtime"
 )
 
 func (bench *Bench) http(conn *conn.Conn, w http.ResponseWriter, r *http.Request) {
-	id, err := runtime.UUID(16, "0123456789abcdef")
+	id, err := conn.NewLinkID()
 	if err != nil {
 		logging.Error("failed to generate link_id for bench: %s, err=%v",
 			bench.Name, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	conn.AddLink(id)
+	err = conn.AddLink(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
 	conn.SendConnectReq(id, bench.cfg)
 	ch := conn.ChanRead(id)
 	<-ch
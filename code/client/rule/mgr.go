@@ -18,7 +18,7 @@ type Link interface {
 
 // Rule rule interface
 type Rule interface {
-	NewLink(id, remote string, localConn net.Conn, remoteConn *conn.Conn) Link
+	NewLink(id, remote string, localConn net.Conn, remoteConn *conn.Conn) (Link, error)
 	GetName() string
 	GetRemote() string
 	GetPort() uint16
@@ -8,19 +8,24 @@ import (
 	"github.com/lwch/logging"
 	"github.com/lwch/natpass/code/client/conn"
 	"github.com/lwch/natpass/code/network"
-	"github.com/lwch/runtime"
 )
 
 // New new shell
 func (shell *Shell) New(conn *conn.Conn, w http.ResponseWriter, r *http.Request) {
-	id, err := runtime.UUID(16, "0123456789abcdef")
+	id, err := conn.NewLinkID()
 	if err != nil {
 		logging.Error("failed to generate link_id for shell: %s, err=%v",
 			shell.Name, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	link := shell.NewLink(id, shell.cfg.Target, nil, conn).(*Link)
+	lk, err := shell.NewLink(id, shell.cfg.Target, nil, conn)
+	if err != nil {
+		logging.Error("create shell %s by rule %s failed, err=%v", id, shell.Name, err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	link := lk.(*Link)
 	conn.SendConnectReq(id, shell.cfg)
 	ch := conn.ChanRead(id)
 	timeout := time.After(shell.readTimeout)
@@ -52,6 +52,7 @@ func (link *Link) Close() {
 		p.Kill()
 	}
 	link.remote.SendDisconnect(link.target, link.id)
+	link.remote.RemoveLink(link.id)
 	link.parent.remove(link.id)
 }
 
@@ -64,9 +65,17 @@ func (link *Link) Forward() {
 func (link *Link) remoteRead() {
 	defer utils.Recover("remoteRead")
 	defer link.Close()
+	ctx := link.remote.Context()
 	ch := link.remote.ChanRead(link.id)
 	for {
-		msg := <-ch
+		var msg *network.Msg
+		select {
+		case msg = <-ch:
+		case <-ctx.Done():
+			logging.Info("shell %s link %s closed by connection of %s",
+				link.parent.Name, link.id, conn.ClientID(ctx))
+			return
+		}
 		if msg == nil {
 			return
 		}
@@ -88,6 +97,7 @@ func (link *Link) remoteRead() {
 			logging.Info("shell %s link %s closed by remote", link.parent.Name, link.id)
 			return
 		}
+		link.remote.Release(msg)
 	}
 }
 
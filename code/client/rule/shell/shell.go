@@ -11,9 +11,13 @@ import (
 	"github.com/lwch/natpass/code/client/conn"
 	"github.com/lwch/natpass/code/client/global"
 	"github.com/lwch/natpass/code/client/rule"
+	"github.com/lwch/natpass/code/network"
 	"github.com/lwch/runtime"
 )
 
+// coalesceMax flush coalesced shell data when reached this size
+const coalesceMax = 4096
+
 // Shell shell handler
 type Shell struct {
 	sync.RWMutex
@@ -35,9 +39,19 @@ func New(cfg global.Rule, readTimeout, writeTimeout time.Duration) *Shell {
 	}
 }
 
-// NewLink new link
-func (shell *Shell) NewLink(id, remote string, localConn net.Conn, remoteConn *conn.Conn) rule.Link {
-	remoteConn.AddLink(id)
+// NewLink new link, localConn is closed if the link can not be added
+func (shell *Shell) NewLink(id, remote string, localConn net.Conn, remoteConn *conn.Conn) (rule.Link, error) {
+	err := remoteConn.AddLink(id, network.Msg_shell_resize, network.Msg_shell_data)
+	if err != nil {
+		if localConn != nil {
+			localConn.Close()
+		}
+		return nil, err
+	}
+	remoteConn.SetLinkPriority(id, conn.PriorityRealtime)
+	if shell.cfg.Coalesce > 0 {
+		remoteConn.SetLinkCoalesce(id, shell.cfg.Coalesce, coalesceMax)
+	}
 	link := &Link{
 		parent: shell,
 		id:     id,
@@ -47,7 +61,7 @@ func (shell *Shell) NewLink(id, remote string, localConn net.Conn, remoteConn *c
 	shell.Lock()
 	shell.links[link.id] = link
 	shell.Unlock()
-	return link
+	return link, nil
 }
 
 // GetName get shell rule name
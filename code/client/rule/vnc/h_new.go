@@ -9,7 +9,6 @@ import (
 	"github.com/lwch/logging"
 	"github.com/lwch/natpass/code/client/conn"
 	"github.com/lwch/natpass/code/network"
-	"github.com/lwch/runtime"
 )
 
 // New new vnc
@@ -27,7 +26,7 @@ func (v *VNC) New(conn *conn.Conn, w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		showCursor = false
 	}
-	id, err := runtime.UUID(16, "0123456789abcdef")
+	id, err := conn.NewLinkID()
 	if err != nil {
 		logging.Error("failed to generate link_id for vnc: %s, err=%v",
 			v.Name, err)
@@ -37,8 +36,14 @@ func (v *VNC) New(conn *conn.Conn, w http.ResponseWriter, r *http.Request) {
 	if v.link != nil {
 		conn.SendDisconnect(v.link.target, v.link.id)
 	}
+	lk, err := v.NewLink(id, v.cfg.Target, nil, conn)
+	if err != nil {
+		logging.Error("create vnc %s by rule %s failed, err=%v", id, v.Name, err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	v.link = lk.(*Link)
 	conn.SendConnectVnc(id, v.cfg, quality, showCursor)
-	v.link = v.NewLink(id, v.cfg.Target, nil, conn).(*Link)
 	ch := conn.ChanRead(id)
 	timeout := time.After(v.readTimeout)
 	for {
@@ -83,9 +83,15 @@ func (link *Link) Forward() {
 
 func (link *Link) remoteRead() {
 	defer link.close()
+	ctx := link.remote.Context()
 	ch := link.remote.ChanRead(link.id)
 	for {
-		msg := <-ch
+		var msg *network.Msg
+		select {
+		case msg = <-ch:
+		case <-ctx.Done():
+			return
+		}
 		if msg == nil {
 			return
 		}
@@ -114,6 +120,7 @@ func (link *Link) remoteRead() {
 			logging.Info("link %s disconnected", link.id)
 			return
 		}
+		link.remote.Release(msg)
 	}
 }
 
@@ -157,6 +164,7 @@ func (link *Link) close() {
 		link.ps.Close()
 	}
 	link.remote.SendDisconnect(link.target, link.id)
+	link.remote.RemoveLink(link.id)
 }
 
 func cut(src *image.RGBA, rect image.Rectangle) *image.RGBA {
@@ -37,9 +37,23 @@ func New(cfg global.Rule, readTimeout, writeTimeout time.Duration) *VNC {
 	}
 }
 
-// NewLink new link
-func (v *VNC) NewLink(id, remote string, localConn net.Conn, remoteConn *conn.Conn) rule.Link {
-	remoteConn.AddLink(id)
+// NewLink new link, localConn is closed if the link can not be added
+func (v *VNC) NewLink(id, remote string, localConn net.Conn, remoteConn *conn.Conn) (rule.Link, error) {
+	err := remoteConn.AddLink(id,
+		network.Msg_vnc_ctrl,
+		network.Msg_vnc_image,
+		network.Msg_vnc_mouse,
+		network.Msg_vnc_keyboard,
+		network.Msg_vnc_cad,
+		network.Msg_vnc_scroll,
+		network.Msg_vnc_clipboard)
+	if err != nil {
+		if localConn != nil {
+			localConn.Close()
+		}
+		return nil, err
+	}
+	remoteConn.SetLinkPriority(id, conn.PriorityRealtime)
 	link := &Link{
 		parent: v,
 		id:     id,
@@ -50,7 +64,7 @@ func (v *VNC) NewLink(id, remote string, localConn net.Conn, remoteConn *conn.Co
 		v.link.close()
 	}
 	v.link = link
-	return link
+	return link, nil
 }
 
 // GetName get vnc rule name
@@ -0,0 +1,59 @@
+package network
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec message encoder and decoder
+type Codec interface {
+	Name() string
+	Marshal(*Msg) ([]byte, error)
+	Unmarshal([]byte, *Msg) error
+}
+
+// ProtobufCodec protobuf codec, default
+var ProtobufCodec Codec = protobufCodec{}
+
+// JSONCodec json codec, human readable for debugging
+var JSONCodec Codec = jsonCodec{}
+
+// GetCodec get codec by name, returns nil if not supported
+func GetCodec(name string) Codec {
+	switch name {
+	case "", ProtobufCodec.Name():
+		return ProtobufCodec
+	case JSONCodec.Name():
+		return JSONCodec
+	default:
+		return nil
+	}
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string {
+	return "protobuf"
+}
+
+func (protobufCodec) Marshal(m *Msg) ([]byte, error) {
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Unmarshal(data []byte, m *Msg) error {
+	return proto.Unmarshal(data, m)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Marshal(m *Msg) ([]byte, error) {
+	return protojson.Marshal(m)
+}
+
+func (jsonCodec) Unmarshal(data []byte, m *Msg) error {
+	return protojson.Unmarshal(data, m)
+}
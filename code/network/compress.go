@@ -0,0 +1,78 @@
+package network
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"math"
+	"sync/atomic"
+)
+
+// compressMagic first byte of compressed message, field number 0 is invalid in protobuf
+// and it is not a valid json start, so the uncompressed message never starts with it
+const compressMagic = 0x00
+
+var errDecompress = errors.New("invalid compressed data")
+
+// compress compress data, returns data if compressed data is not smaller
+func compress(data []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(compressMagic)
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return data
+	}
+	w.Write(data)
+	if w.Close() != nil || buf.Len() >= len(data) {
+		return data
+	}
+	return buf.Bytes()
+}
+
+// decompress decompress data if it is compressed
+func decompress(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != compressMagic {
+		return data, nil
+	}
+	r := flate.NewReader(bytes.NewReader(data[1:]))
+	defer r.Close()
+	ret, err := io.ReadAll(io.LimitReader(r, math.MaxUint16+1))
+	if err != nil {
+		return nil, errDecompress
+	}
+	if len(ret) > math.MaxUint16 {
+		return nil, errTooLong
+	}
+	return ret, nil
+}
+
+// CompressStats cumulative bytes of compressed messages before and after compression
+type CompressStats struct {
+	InRaw   uint64 // must be first for 64-bit atomic alignment
+	InComp  uint64
+	OutRaw  uint64
+	OutComp uint64
+}
+
+func (s *CompressStats) addIn(raw, comp int) {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.InRaw, uint64(raw))
+	atomic.AddUint64(&s.InComp, uint64(comp))
+}
+
+func (s *CompressStats) addOut(raw, comp int) {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.OutRaw, uint64(raw))
+	atomic.AddUint64(&s.OutComp, uint64(comp))
+}
+
+// Load get stats of received and sent compressed messages
+func (s *CompressStats) Load() (inRaw, inComp, outRaw, outComp uint64) {
+	return atomic.LoadUint64(&s.InRaw), atomic.LoadUint64(&s.InComp),
+		atomic.LoadUint64(&s.OutRaw), atomic.LoadUint64(&s.OutComp)
+}
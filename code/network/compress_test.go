@@ -0,0 +1,62 @@
+package network
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompressStats(t *testing.T) {
+	a, b := net.Pipe()
+	w := NewConn(a)
+	r := NewConn(b)
+	defer w.Close()
+	defer r.Close()
+	var out, in CompressStats
+	w.SetCompressStats(&out)
+	r.SetCompressStats(&in)
+
+	var msg Msg
+	msg.XType = Msg_handshake
+	msg.Payload = &Msg_Hsp{
+		Hsp: &HandshakePayload{
+			Ext: map[string]string{"tags": strings.Repeat("natpass", 100)},
+		},
+	}
+	if err := w.WriteCompressedMessage(&msg, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := r.ReadMessage(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GetHsp().GetExt()["tags"] != msg.GetHsp().GetExt()["tags"] {
+		t.Fatal("unexpected message after decompressed")
+	}
+	_, _, outRaw, outComp := out.Load()
+	if outRaw == 0 || outComp == 0 || outComp >= outRaw {
+		t.Fatalf("unexpected out stats: raw=%d, comp=%d", outRaw, outComp)
+	}
+	inRaw, inComp, _, _ := in.Load()
+	if inRaw != outRaw || inComp != outComp {
+		t.Fatalf("unexpected in stats: raw=%d, comp=%d", inRaw, inComp)
+	}
+}
+
+func TestCompressStatsUncompressed(t *testing.T) {
+	a, b := net.Pipe()
+	w := NewConn(a)
+	defer w.Close()
+	defer b.Close()
+	var out CompressStats
+	w.SetCompressStats(&out)
+	var msg Msg
+	msg.XType = Msg_keepalive
+	if err := w.WriteMessage(&msg, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, outRaw, _ := out.Load(); outRaw != 0 {
+		t.Fatalf("uncompressed message counted: %d", outRaw)
+	}
+}
@@ -0,0 +1,62 @@
+package network
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latency buckets upper bound, the last bucket has no upper bound
+var histogramBuckets = []time.Duration{
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+}
+
+// Histogram lock free latency histogram
+type Histogram struct {
+	count   uint64 // must be first for 64-bit atomic alignment
+	sum     uint64 // nanoseconds
+	buckets [9]uint64
+}
+
+// HistogramSnapshot snapshot of histogram, buckets is keyed by upper bound like 10µs or +Inf
+type HistogramSnapshot struct {
+	Count   uint64            `json:"count"`
+	Sum     time.Duration     `json:"sum"`
+	Buckets map[string]uint64 `json:"buckets"`
+}
+
+// Observe add duration to histogram
+func (h *Histogram) Observe(d time.Duration) {
+	i := 0
+	for ; i < len(histogramBuckets); i++ {
+		if d <= histogramBuckets[i] {
+			break
+		}
+	}
+	atomic.AddUint64(&h.buckets[i], 1)
+	atomic.AddUint64(&h.sum, uint64(d))
+	atomic.AddUint64(&h.count, 1)
+}
+
+// Snapshot get current values of histogram
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	ret := HistogramSnapshot{
+		Count:   atomic.LoadUint64(&h.count),
+		Sum:     time.Duration(atomic.LoadUint64(&h.sum)),
+		Buckets: make(map[string]uint64, len(h.buckets)),
+	}
+	for i := range h.buckets {
+		name := "+Inf"
+		if i < len(histogramBuckets) {
+			name = histogramBuckets[i].String()
+		}
+		ret.Buckets[name] = atomic.LoadUint64(&h.buckets[i])
+	}
+	return ret
+}
@@ -10,24 +10,54 @@ import (
 	"math"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lwch/logging"
-	"google.golang.org/protobuf/proto"
 )
 
 var errTooLong = errors.New("too long")
 var errChecksum = errors.New("invalid checksum")
 var errTimeout = errors.New("timeout")
 
+// ErrTooLarge message exceeded the negotiated max size
+var ErrTooLarge = errors.New("message too large")
+
+// MaxMessageSize max size of framed message
+const MaxMessageSize = math.MaxUint16
+
+// NegotiateMaxSize get the max message size accepted by both sides, 0 means MaxMessageSize
+func NegotiateMaxSize(a, b uint32) uint32 {
+	if a == 0 || a > MaxMessageSize {
+		a = MaxMessageSize
+	}
+	if b == 0 || b > MaxMessageSize {
+		b = MaxMessageSize
+	}
+	if a < b {
+		return a
+	}
+	return b
+}
+
 // Conn network connection
 type Conn struct {
 	c        net.Conn
 	lockRead sync.Mutex
 	sizeRead [6]byte
 	chWrite  chan []byte
+	chHigh   chan []byte
+	chLow    chan []byte
 	ctx      context.Context
 	cancel   context.CancelFunc
+	codec    Codec
+	encode   *Histogram
+	decode   *Histogram
+	maxSize  uint32 // negotiated max message size, 0 is MaxMessageSize
+	stats    *CompressStats
+	pool     *MessagePool
+	detached int32
+	done     chan struct{}
 }
 
 // NewConn create connection
@@ -36,20 +66,67 @@ func NewConn(c net.Conn) *Conn {
 	conn := &Conn{
 		c:       c,
 		chWrite: make(chan []byte, 1024),
+		chHigh:  make(chan []byte, 1024),
+		chLow:   make(chan []byte, 1024),
 		ctx:     ctx,
 		cancel:  cancel,
+		codec:   ProtobufCodec,
+		done:    make(chan struct{}),
 	}
 	go conn.loopWrite()
 	return conn
 }
 
+// SetCodec set message codec, the handshake message is always encoded by protobuf
+func (c *Conn) SetCodec(codec Codec) {
+	c.codec = codec
+}
+
+// SetHistogram set histogram of encode and decode latency, nil to disable
+func (c *Conn) SetHistogram(encode, decode *Histogram) {
+	c.encode = encode
+	c.decode = decode
+}
+
+// SetCompressStats set stats of messages written by WriteCompressedMessage and
+// compressed messages read, nil to disable
+func (c *Conn) SetCompressStats(stats *CompressStats) {
+	c.stats = stats
+}
+
+// SetMaxSize set max message size negotiated in handshake,
+// larger messages are rejected in both read and write
+func (c *Conn) SetMaxSize(n uint32) {
+	atomic.StoreUint32(&c.maxSize, n)
+}
+
+// MaxSize get max message size
+func (c *Conn) MaxSize() uint32 {
+	n := atomic.LoadUint32(&c.maxSize)
+	if n == 0 {
+		return MaxMessageSize
+	}
+	return n
+}
+
 // Close close connection
 func (c *Conn) Close() {
 	c.c.Close()
 	c.cancel()
 }
 
-func (c *Conn) read(timeout time.Duration) (uint32, uint16, []byte, error) {
+// Detach stop reading and writing messages after the queued messages are written,
+// returns the underlying connection to be used as raw stream, e.g. spliced by relay
+func (c *Conn) Detach() net.Conn {
+	atomic.StoreInt32(&c.detached, 1)
+	c.cancel()
+	<-c.done
+	c.c.SetDeadline(time.Time{})
+	return c.c
+}
+
+// read read framed message into buffer from readBuffers, the caller puts it back after decoded
+func (c *Conn) read(timeout time.Duration) (uint32, uint16, *[]byte, error) {
 	c.lockRead.Lock()
 	defer c.lockRead.Unlock()
 	c.c.SetReadDeadline(time.Now().Add(timeout))
@@ -59,46 +136,103 @@ func (c *Conn) read(timeout time.Duration) (uint32, uint16, []byte, error) {
 	}
 	size := binary.BigEndian.Uint16(c.sizeRead[:])
 	enc := binary.BigEndian.Uint32(c.sizeRead[2:])
-	buf := make([]byte, size)
-	_, err = io.ReadFull(c.c, buf)
+	buf := getReadBuffer(size)
+	_, err = io.ReadFull(c.c, *buf)
 	if err != nil {
+		putReadBuffer(buf)
 		return 0, 0, nil, err
 	}
+	// the payload is consumed to keep the stream in sync
+	if uint32(size) > c.MaxSize() {
+		putReadBuffer(buf)
+		return 0, 0, nil, ErrTooLarge
+	}
 	return enc, size, buf, nil
 }
 
-// ReadMessage read message with timeout
+// SetMessagePool set pool of messages returned by ReadMessage, nil to disable
+func (c *Conn) SetMessagePool(pool *MessagePool) {
+	c.pool = pool
+}
+
+// ReadMessage read message with timeout, the message is taken from the pool set by SetMessagePool
+// and the caller owns it until released
 func (c *Conn) ReadMessage(timeout time.Duration) (*Msg, uint16, error) {
-	enc, size, buf, err := c.read(timeout)
+	enc, size, raw, err := c.read(timeout)
 	if err != nil {
 		return nil, 0, err
 	}
+	defer putReadBuffer(raw)
+	buf := *raw
+	begin := time.Now()
 	if crc32.ChecksumIEEE(buf) != enc {
 		return nil, 0, errChecksum
 	}
-	var msg Msg
-	err = proto.Unmarshal(buf, &msg)
+	comp := len(buf)
+	compressed := comp > 0 && buf[0] == compressMagic
+	buf, err = decompress(buf)
 	if err != nil {
 		return nil, 0, err
 	}
-	return &msg, size, nil
+	if compressed {
+		c.stats.addIn(len(buf), comp)
+	}
+	msg := c.pool.get()
+	err = c.codec.Unmarshal(buf, msg)
+	if err != nil {
+		c.pool.Release(msg)
+		return nil, 0, err
+	}
+	if c.decode != nil {
+		c.decode.Observe(time.Since(begin))
+	}
+	return msg, size, nil
 }
 
 // WriteMessage write message with timeout
 func (c *Conn) WriteMessage(m *Msg, timeout time.Duration) error {
-	data, err := proto.Marshal(m)
+	return c.writeMessage(m, false, timeout)
+}
+
+// WriteCompressedMessage write message compressed by deflate with timeout,
+// the message is not compressed if it can not be smaller
+func (c *Conn) WriteCompressedMessage(m *Msg, timeout time.Duration) error {
+	return c.writeMessage(m, true, timeout)
+}
+
+func (c *Conn) writeMessage(m *Msg, compressed bool, timeout time.Duration) error {
+	begin := time.Now()
+	data, err := c.codec.Marshal(m)
 	if err != nil {
 		return err
 	}
+	if compressed {
+		raw := len(data)
+		data = compress(data)
+		c.stats.addOut(raw, len(data))
+	}
 	if len(data) > math.MaxUint16 {
 		return errTooLong
 	}
+	if uint32(len(data)) > c.MaxSize() {
+		return ErrTooLarge
+	}
 	buf := make([]byte, len(data)+len(c.sizeRead))
 	binary.BigEndian.PutUint16(buf, uint16(len(data)))
 	binary.BigEndian.PutUint32(buf[2:], crc32.ChecksumIEEE(data))
 	copy(buf[len(c.sizeRead):], data)
+	if c.encode != nil {
+		c.encode.Observe(time.Since(begin))
+	}
+	ch := c.chWrite
+	switch m.GetPrio() {
+	case Msg_high:
+		ch = c.chHigh
+	case Msg_low:
+		ch = c.chLow
+	}
 	select {
-	case c.chWrite <- buf:
+	case ch <- buf:
 		return nil
 	case <-time.After(timeout):
 		return errTimeout
@@ -116,13 +250,30 @@ func (c *Conn) LocalAddr() net.Addr {
 }
 
 func (c *Conn) loopWrite() {
-	defer c.Close()
+	defer close(c.done)
 	for {
-		select {
-		case <-c.ctx.Done():
+		data := c.nextWrite()
+		if data == nil {
+			break
+		}
+		_, err := io.Copy(c.c, bytes.NewReader(data))
+		if err != nil {
+			// closed by reader, e.g. dropped in handshake
+			if !errors.Is(err, net.ErrClosed) {
+				logging.Error("write data: %v", err)
+			}
+			c.Close()
 			return
-		case data := <-c.chWrite:
-			_, err := io.Copy(c.c, bytes.NewReader(data))
+		}
+	}
+	if atomic.LoadInt32(&c.detached) == 0 {
+		c.Close()
+		return
+	}
+	// flush queued messages before detached
+	for _, ch := range []chan []byte{c.chHigh, c.chWrite, c.chLow} {
+		for len(ch) > 0 {
+			_, err := io.Copy(c.c, bytes.NewReader(<-ch))
 			if err != nil {
 				logging.Error("write data: %v", err)
 				return
@@ -130,3 +281,55 @@ func (c *Conn) loopWrite() {
 		}
 	}
 }
+
+// nextWrite get next data to write by message priority, returns nil if closed
+func (c *Conn) nextWrite() []byte {
+	select {
+	case data := <-c.chHigh:
+		return data
+	default:
+	}
+	select {
+	case data := <-c.chHigh:
+		return data
+	case data := <-c.chWrite:
+		return data
+	default:
+	}
+	select {
+	case <-c.ctx.Done():
+		return nil
+	case data := <-c.chHigh:
+		return data
+	case data := <-c.chWrite:
+		return data
+	case data := <-c.chLow:
+		return data
+	}
+}
+
+// RejectDuplicateID handshake rejected because the client id is used by another running client
+const RejectDuplicateID = "duplicate_id"
+
+// RejectRelay relay request rejected because relay is disabled, the next hop is not allowed or unreachable
+const RejectRelay = "relay"
+
+// limits of handshake ext
+const (
+	MaxHandshakeExt      = 32
+	MaxHandshakeExtKey   = 64
+	MaxHandshakeExtValue = 256
+)
+
+// ValidHandshakeExt check size of handshake ext
+func ValidHandshakeExt(ext map[string]string) bool {
+	if len(ext) > MaxHandshakeExt {
+		return false
+	}
+	for k, v := range ext {
+		if len(k) > MaxHandshakeExtKey || len(v) > MaxHandshakeExtValue {
+			return false
+		}
+	}
+	return true
+}
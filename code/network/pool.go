@@ -0,0 +1,56 @@
+package network
+
+import "sync"
+
+// readBuffers buffers of framed messages, they are reused after decoded
+// because codecs copy bytes and strings out of the input
+var readBuffers = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, MaxMessageSize)
+		return &buf
+	},
+}
+
+func getReadBuffer(size uint16) *[]byte {
+	buf := readBuffers.Get().(*[]byte)
+	*buf = (*buf)[:size]
+	return buf
+}
+
+func putReadBuffer(buf *[]byte) {
+	readBuffers.Put(buf)
+}
+
+// MessagePool pool of decoded messages set by Conn.SetMessagePool,
+// a message read from the connection is owned by its consumer until released by Release
+// and must not be used after that, messages never released are collected by gc as usual
+type MessagePool struct {
+	pool sync.Pool
+}
+
+// NewMessagePool create message pool
+func NewMessagePool() *MessagePool {
+	return &MessagePool{
+		pool: sync.Pool{
+			New: func() interface{} {
+				return new(Msg)
+			},
+		},
+	}
+}
+
+func (p *MessagePool) get() *Msg {
+	if p == nil {
+		return new(Msg)
+	}
+	return p.pool.Get().(*Msg)
+}
+
+// Release reset message and put it back to pool, nil pool or message is ignored
+func (p *MessagePool) Release(msg *Msg) {
+	if p == nil || msg == nil {
+		return
+	}
+	msg.Reset()
+	p.pool.Put(msg)
+}
@@ -0,0 +1,74 @@
+package network
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"net"
+	"testing"
+	"time"
+)
+
+// loopConn connection reads the same frame endlessly
+type loopConn struct {
+	net.Conn
+	frame []byte
+	off   int
+}
+
+func (c *loopConn) Read(p []byte) (int, error) {
+	n := copy(p, c.frame[c.off:])
+	c.off = (c.off + n) % len(c.frame)
+	return n, nil
+}
+
+func (c *loopConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func benchFrame(b *testing.B) []byte {
+	var msg Msg
+	msg.From = "peer"
+	msg.To = "me"
+	msg.XType = Msg_forward
+	msg.LinkId = "link"
+	msg.Payload = &Msg_XData{
+		XData: &Data{Data: make([]byte, 1024)},
+	}
+	data, err := ProtobufCodec.Marshal(&msg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	frame := make([]byte, len(data)+6)
+	binary.BigEndian.PutUint16(frame, uint16(len(data)))
+	binary.BigEndian.PutUint32(frame[2:], crc32.ChecksumIEEE(data))
+	copy(frame[6:], data)
+	return frame
+}
+
+func BenchmarkReadMessage(b *testing.B) {
+	for _, pooled := range []bool{false, true} {
+		name := "pool=off"
+		if pooled {
+			name = "pool=on"
+		}
+		b.Run(name, func(b *testing.B) {
+			c := &Conn{
+				c:     &loopConn{frame: benchFrame(b)},
+				codec: ProtobufCodec,
+			}
+			if pooled {
+				c.SetMessagePool(NewMessagePool())
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				msg, _, err := c.ReadMessage(time.Second)
+				if err != nil {
+					b.Fatal(err)
+				}
+				// the consumer is done with the message
+				c.pool.Release(msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package network
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsConn net.Conn over websocket, each write is sent in a binary frame,
+// read deadline is handled locally because websocket connection can not
+// be used after read timeout
+type wsConn struct {
+	ws        *websocket.Conn
+	ch        chan []byte
+	err       error
+	buf       []byte
+	lockRead  sync.Mutex
+	deadline  time.Time
+	lockWrite sync.Mutex
+	closeOnce sync.Once
+}
+
+// NewWebSocketConn wrap websocket connection to net.Conn
+func NewWebSocketConn(ws *websocket.Conn) net.Conn {
+	c := &wsConn{
+		ws: ws,
+		ch: make(chan []byte, 16),
+	}
+	go c.loopRead()
+	return c
+}
+
+func (c *wsConn) loopRead() {
+	defer close(c.ch)
+	for {
+		t, data, err := c.ws.ReadMessage()
+		if err != nil {
+			c.err = err
+			return
+		}
+		if t != websocket.BinaryMessage {
+			continue
+		}
+		c.ch <- data
+	}
+}
+
+func (c *wsConn) Read(p []byte) (int, error) {
+	if len(c.buf) == 0 {
+		c.lockRead.Lock()
+		deadline := c.deadline
+		c.lockRead.Unlock()
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			t := time.NewTimer(time.Until(deadline))
+			defer t.Stop()
+			timeout = t.C
+		}
+		select {
+		case data, ok := <-c.ch:
+			if !ok {
+				return 0, c.err
+			}
+			c.buf = data
+		case <-timeout:
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+func (c *wsConn) Write(p []byte) (int, error) {
+	c.lockWrite.Lock()
+	defer c.lockWrite.Unlock()
+	err := c.ws.WriteMessage(websocket.BinaryMessage, p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *wsConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.ws.Close()
+	})
+	return err
+}
+
+func (c *wsConn) LocalAddr() net.Addr {
+	return c.ws.LocalAddr()
+}
+
+func (c *wsConn) RemoteAddr() net.Addr {
+	return c.ws.RemoteAddr()
+}
+
+func (c *wsConn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)
+	return c.SetWriteDeadline(t)
+}
+
+func (c *wsConn) SetReadDeadline(t time.Time) error {
+	c.lockRead.Lock()
+	c.deadline = t
+	c.lockRead.Unlock()
+	return nil
+}
+
+func (c *wsConn) SetWriteDeadline(t time.Time) error {
+	return c.ws.SetWriteDeadline(t)
+}
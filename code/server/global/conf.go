@@ -14,14 +14,21 @@ import (
 // Configure server configure
 type Configure struct {
 	Listen       uint16
+	WSListen     uint16
+	WSPath       string
 	Enc          [md5.Size]byte
 	TLSKey       string
 	TLSCrt       string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	MaxMsgSize   utils.Bytes
 	LogDir       string
 	LogSize      utils.Bytes
 	LogRotate    int
+	Push         map[string]string // configure pushed to clients
+	Relay        bool
+	RelayAllow   []string      // allowed next hops of relay, empty means any
+	MetricsLog   time.Duration // interval of logging metrics reported by clients, 0 means disabled
 }
 
 // LoadConf load configure file
@@ -32,18 +39,32 @@ func LoadConf(dir string) *Configure {
 		Link   struct {
 			ReadTimeout  time.Duration `yaml:"read_timeout"`
 			WriteTimeout time.Duration `yaml:"write_timeout"`
+			MaxMsgSize   utils.Bytes   `yaml:"max_message_size"`
 		} `yaml:"link"`
 		Log struct {
 			Dir    string      `yaml:"dir"`
 			Size   utils.Bytes `yaml:"size"`
 			Rotate int         `yaml:"rotate"`
 		} `yaml:"log"`
+		WebSocket struct {
+			Listen uint16 `yaml:"listen"`
+			Path   string `yaml:"path"`
+		} `yaml:"websocket"`
 		TLS struct {
 			Key string `yaml:"key"`
 			Crt string `yaml:"crt"`
 		} `yaml:"tls"`
+		Push  map[string]string `yaml:"push"`
+		Relay struct {
+			Enabled bool     `yaml:"enabled"`
+			Allow   []string `yaml:"allow"`
+		} `yaml:"relay"`
+		MetricsLog time.Duration `yaml:"metrics_log"`
 	}
 	runtime.Assert(yaml.Decode(dir, &cfg))
+	if len(cfg.WebSocket.Path) == 0 {
+		cfg.WebSocket.Path = "/natpass"
+	}
 	if !filepath.IsAbs(cfg.Log.Dir) {
 		dir, err := os.Executable()
 		runtime.Assert(err)
@@ -51,13 +72,20 @@ func LoadConf(dir string) *Configure {
 	}
 	return &Configure{
 		Listen:       cfg.Listen,
+		WSListen:     cfg.WebSocket.Listen,
+		WSPath:       cfg.WebSocket.Path,
 		Enc:          md5.Sum([]byte(cfg.Secret)),
 		TLSKey:       cfg.TLS.Key,
 		TLSCrt:       cfg.TLS.Crt,
 		ReadTimeout:  cfg.Link.ReadTimeout,
 		WriteTimeout: cfg.Link.WriteTimeout,
+		MaxMsgSize:   cfg.Link.MaxMsgSize,
 		LogDir:       cfg.Log.Dir,
 		LogSize:      cfg.Log.Size,
 		LogRotate:    cfg.Log.Rotate,
+		Push:         cfg.Push,
+		Relay:        cfg.Relay.Enabled,
+		RelayAllow:   cfg.Relay.Allow,
+		MetricsLog:   cfg.MetricsLog,
 	}
 }
@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"errors"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lwch/logging"
@@ -11,11 +13,15 @@ import (
 
 type client struct {
 	sync.RWMutex
-	id      string
-	parent  *clients
-	conn    *network.Conn
-	updated time.Time
-	links   map[string]struct{} // link id => struct{}
+	id       string
+	parent   *clients
+	conn     *network.Conn
+	ctrl     *network.Conn // control connection, nil if not used
+	updated  time.Time
+	links    map[string]struct{} // link id => struct{}
+	ext      map[string]string   // handshake metadata
+	instance string              // random id of client process
+	metrics  clientMetrics       // last metrics reported on keepalive
 }
 
 func (c *client) close() {
@@ -26,6 +32,7 @@ func (c *client) close() {
 		c.Unlock()
 	}
 	c.conn.Close()
+	c.closeControl(c.getControl())
 	logging.Info("client %s connection closed", c.id)
 }
 
@@ -47,6 +54,10 @@ func (c *client) run() {
 			if strings.Contains(err.Error(), "i/o timeout") {
 				continue
 			}
+			if errors.Is(err, network.ErrTooLarge) {
+				logging.Error("drop message from %s larger than %d bytes", c.id, c.conn.MaxSize())
+				continue
+			}
 			logging.Error("read message from %s: %v", c.id, err)
 			return
 		}
@@ -55,8 +66,83 @@ func (c *client) run() {
 	}
 }
 
+// runControl read messages from control connection until it is closed
+func (c *client) runControl(conn *network.Conn) {
+	for {
+		msg, size, err := conn.ReadMessage(c.parent.parent.cfg.ReadTimeout)
+		if err != nil {
+			if strings.Contains(err.Error(), "i/o timeout") {
+				if c.getControl() == conn {
+					continue
+				}
+				return
+			}
+			logging.Error("read control message from %s: %v", c.id, err)
+			return
+		}
+		c.updated = time.Now()
+		c.parent.parent.onMessage(c, conn, msg, size)
+	}
+}
+
+func (c *client) getControl() *network.Conn {
+	c.RLock()
+	defer c.RUnlock()
+	return c.ctrl
+}
+
+func (c *client) setControl(conn *network.Conn) {
+	c.Lock()
+	old := c.ctrl
+	c.ctrl = conn
+	c.Unlock()
+	if old != nil {
+		old.Close()
+	}
+}
+
+// closeControl close the control connection if it is still in use
+func (c *client) closeControl(conn *network.Conn) {
+	if conn == nil {
+		return
+	}
+	c.Lock()
+	if c.ctrl == conn {
+		c.ctrl = nil
+	}
+	c.Unlock()
+	conn.Close()
+}
+
+// writeMessage write message, the control messages are sent on control connection if exists
 func (c *client) writeMessage(msg *network.Msg) error {
-	return c.conn.WriteMessage(msg, c.parent.parent.cfg.WriteTimeout)
+	timeout := c.parent.parent.cfg.WriteTimeout
+	if isControl(msg) {
+		if ctrl := c.getControl(); ctrl != nil {
+			err := ctrl.WriteMessage(msg, timeout)
+			if err == nil {
+				return nil
+			}
+			logging.Error("write control message to %s: %v", c.id, err)
+			c.closeControl(ctrl)
+		}
+	}
+	return c.conn.WriteMessage(msg, timeout)
+}
+
+func isControl(msg *network.Msg) bool {
+	switch msg.GetXType() {
+	case network.Msg_keepalive,
+		network.Msg_ack,
+		network.Msg_connect_req,
+		network.Msg_connect_rep,
+		network.Msg_disconnect,
+		network.Msg_config,
+		network.Msg_config_ack,
+		network.Msg_migrate:
+		return true
+	}
+	return false
 }
 
 func (c *client) addLink(id string) {
@@ -87,12 +173,58 @@ func (c *client) sendClose(id string) {
 	msg.To = c.id
 	msg.XType = network.Msg_disconnect
 	msg.LinkId = id
-	c.conn.WriteMessage(&msg, c.parent.parent.cfg.WriteTimeout)
+	c.writeMessage(&msg)
 	c.Lock()
 	delete(c.links, id)
 	c.Unlock()
 }
 
+func (c *client) sendAck(id uint64) {
+	var msg network.Msg
+	msg.From = "server"
+	msg.To = c.id
+	msg.XType = network.Msg_ack
+	msg.ReqId = id
+	err := c.writeMessage(&msg)
+	if err != nil {
+		logging.Error("send ack %d to %s: %v", id, c.id, err)
+	}
+}
+
+// sendHandshakeAck acknowledge handshake with negotiated max message size
+func (c *client) sendHandshakeAck(conn *network.Conn, id uint64) {
+	var msg network.Msg
+	msg.From = "server"
+	msg.To = c.id
+	msg.XType = network.Msg_ack
+	msg.ReqId = id
+	msg.Payload = &network.Msg_Hsp{
+		Hsp: &network.HandshakePayload{
+			MaxSize: conn.MaxSize(),
+		},
+	}
+	err := conn.WriteMessage(&msg, c.parent.parent.cfg.WriteTimeout)
+	if err != nil {
+		logging.Error("send handshake ack %d to %s: %v", id, c.id, err)
+	}
+}
+
+// pushConfig push configure values to client, the client acknowledges by config_ack
+func (c *client) pushConfig(values map[string]string) error {
+	var msg network.Msg
+	msg.From = "server"
+	msg.To = c.id
+	msg.XType = network.Msg_config
+	msg.ReqId = atomic.AddUint64(&c.parent.parent.reqID, 1)
+	msg.Payload = &network.Msg_Cfg{
+		Cfg: &network.ConfigUpdate{
+			Values: values,
+		},
+	}
+	logging.Info("push configure %d to %s: %v", msg.ReqId, c.id, values)
+	return c.writeMessage(&msg)
+}
+
 func (c *client) keepalive() {
 	var msg network.Msg
 	msg.From = "server"
@@ -100,10 +232,14 @@ func (c *client) keepalive() {
 	msg.XType = network.Msg_keepalive
 	for {
 		time.Sleep(10 * time.Second)
-		err := c.writeMessage(&msg)
+		// keepalive both connections to avoid read timeout on client
+		err := c.conn.WriteMessage(&msg, c.parent.parent.cfg.WriteTimeout)
 		if err != nil {
 			logging.Error("send keepalive: %v", err)
 			return
 		}
+		if ctrl := c.getControl(); ctrl != nil {
+			ctrl.WriteMessage(&msg, c.parent.parent.cfg.WriteTimeout)
+		}
 	}
 }
@@ -20,13 +20,30 @@ func newClients(parent *Handler) *clients {
 	}
 }
 
-func (cs *clients) new(id string, conn *network.Conn) *client {
+// duplicateTimeout the existing client is treated as alive if any message received in it
+const duplicateTimeout = 30 * time.Second
+
+// duplicate check the id is used by another running client process,
+// the same process reconnecting replaces the old connection
+func (cs *clients) duplicate(id, instance string) bool {
+	cs.RLock()
+	c := cs.data[id]
+	cs.RUnlock()
+	if c == nil || len(c.instance) == 0 || len(instance) == 0 {
+		return false
+	}
+	return c.instance != instance && time.Since(c.updated) < duplicateTimeout
+}
+
+func (cs *clients) new(id string, conn *network.Conn, ext map[string]string, instance string) *client {
 	cli := &client{
-		id:      id,
-		parent:  cs,
-		conn:    conn,
-		updated: time.Now(),
-		links:   make(map[string]struct{}),
+		id:       id,
+		parent:   cs,
+		conn:     conn,
+		ext:      ext,
+		instance: instance,
+		updated:  time.Now(),
+		links:    make(map[string]struct{}),
 	}
 	cs.Lock()
 	if c, ok := cs.data[id]; ok {
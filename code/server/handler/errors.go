@@ -4,3 +4,7 @@ import "errors"
 
 var errNotHandshake = errors.New("not handshake")
 var errInvalidHandshake = errors.New("invalid handshake")
+var errInvalidHandshakeExt = errors.New("invalid handshake ext")
+
+// ErrClientNotFound client is not connected
+var ErrClientNotFound = errors.New("client not found")
@@ -30,6 +30,7 @@ func (link *link) close() {
 
 // Handler handler
 type Handler struct {
+	reqID     uint64 // request id of pushed configure
 	cfg       *global.Configure
 	clis      *clients
 	lockLinks sync.RWMutex
@@ -43,6 +44,9 @@ func New(cfg *global.Configure) *Handler {
 		links: make(map[string]link),
 	}
 	h.clis = newClients(h)
+	if cfg.MetricsLog > 0 {
+		go h.logMetrics()
+	}
 	return h
 }
 
@@ -56,9 +60,10 @@ func (h *Handler) Handle(conn net.Conn) {
 		}
 		c.Close()
 	}()
+	var msg *network.Msg
 	var err error
 	for i := 0; i < 10; i++ {
-		id, err = h.readHandshake(c)
+		msg, err = h.readHandshake(c)
 		if err != nil {
 			if err == errInvalidHandshake {
 				logging.Error("invalid handshake from %s", c.RemoteAddr().String())
@@ -72,30 +77,114 @@ func (h *Handler) Handle(conn net.Conn) {
 	if err != nil {
 		return
 	}
-	logging.Info("%s connected", id)
+	id = msg.GetFrom()
+	// messages from server are marked by the reserved id
+	if id == "server" {
+		logging.Error("reserved client id %s from %s", id, c.RemoteAddr().String())
+		id = ""
+		return
+	}
+	if msg.GetXType() == network.Msg_relay {
+		h.relay(c, msg)
+		return
+	}
+	hsp := msg.GetHsp()
+	cd := network.GetCodec(hsp.GetCodec())
+	if cd == nil {
+		logging.Error("unsupported codec %s from %s", hsp.GetCodec(), id)
+		return
+	}
+	c.SetCodec(cd)
+	c.SetMaxSize(network.NegotiateMaxSize(hsp.GetMaxSize(), uint32(h.cfg.MaxMsgSize.Bytes())))
+	if hsp.GetControl() {
+		h.handleControl(id, c, msg.GetReqId())
+		return
+	}
+	if h.clis.duplicate(id, hsp.GetInstance()) {
+		logging.Error("duplicate client id %s from %s, rejected", id, c.RemoteAddr().String())
+		reject(c, id, network.RejectDuplicateID, h.cfg.WriteTimeout)
+		return
+	}
+	logging.Info("%s connected, ext=%v", id, hsp.GetExt())
 
-	cli := h.clis.new(id, c)
+	cli := h.clis.new(id, c, hsp.GetExt(), hsp.GetInstance())
+	if msg.GetReqId() > 0 {
+		cli.sendHandshakeAck(c, msg.GetReqId())
+	}
 
 	defer h.clis.close(id)
 	go cli.keepalive()
+	if len(h.cfg.Push) > 0 {
+		if err := cli.pushConfig(h.cfg.Push); err != nil {
+			logging.Error("push configure to %s: %v", id, err)
+		}
+	}
 
 	cli.run()
 }
 
-// readHandshake read handshake message and compare secret encoded from md5
-func (h *Handler) readHandshake(c *network.Conn) (string, error) {
+// reject send handshake rejection with reason
+func reject(c *network.Conn, id, reason string, timeout time.Duration) {
+	var msg network.Msg
+	msg.From = "server"
+	msg.To = id
+	msg.XType = network.Msg_reject
+	msg.Payload = &network.Msg_Hsp{
+		Hsp: &network.HandshakePayload{
+			Reject: reason,
+		},
+	}
+	err := c.WriteMessage(&msg, timeout)
+	if err != nil {
+		logging.Error("send reject to %s: %v", id, err)
+		return
+	}
+	// wait for the message to be sent before connection closed
+	time.Sleep(time.Second)
+}
+
+// handleControl attach control connection to the connected client
+func (h *Handler) handleControl(id string, c *network.Conn, reqID uint64) {
+	// the data connection may be still in handshake
+	var cli *client
+	for i := 0; i < 50 && cli == nil; i++ {
+		cli = h.clis.lookup(id)
+		if cli == nil {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	if cli == nil {
+		logging.Error("control connection of %s without data connection", id)
+		return
+	}
+	logging.Info("%s control connection connected", id)
+	cli.setControl(c)
+	if reqID > 0 {
+		cli.sendHandshakeAck(c, reqID)
+	}
+	defer cli.closeControl(c)
+	cli.runControl(c)
+}
+
+// readHandshake read handshake message and compare secret encoded from md5,
+// the client acknowledges the handshake by req_id
+func (h *Handler) readHandshake(c *network.Conn) (*network.Msg, error) {
 	msg, _, err := c.ReadMessage(5 * time.Second)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if msg.GetXType() != network.Msg_handshake {
-		return "", errNotHandshake
+	if msg.GetXType() != network.Msg_handshake &&
+		msg.GetXType() != network.Msg_relay {
+		return nil, errNotHandshake
 	}
 	n := bytes.Compare(msg.GetHsp().GetEnc(), h.cfg.Enc[:])
 	if n != 0 {
-		return "", errInvalidHandshake
+		return nil, errInvalidHandshake
 	}
-	return msg.GetFrom(), nil
+	if !network.ValidHandshakeExt(msg.GetHsp().GetExt()) {
+		return nil, errInvalidHandshakeExt
+	}
+	return msg, nil
 }
 
 func (h *Handler) getClient(linkID, to string) *client {
@@ -113,9 +202,38 @@ func (h *Handler) getClient(linkID, to string) *client {
 	return h.clis.lookup(to)
 }
 
+// PushConfig push configure values to the connected client, the client applies
+// allowed fields only and acknowledges the result which is logged
+func (h *Handler) PushConfig(id string, values map[string]string) error {
+	cli := h.clis.lookup(id)
+	if cli == nil {
+		return ErrClientNotFound
+	}
+	return cli.pushConfig(values)
+}
+
 func (h *Handler) onMessage(from *client, conn *network.Conn, msg *network.Msg, size uint16) {
 	to := msg.GetTo()
+	if msg.GetXType() == network.Msg_config_ack {
+		cfg := msg.GetCfg()
+		logging.Info("client %s acknowledged configure %d, applied: %v, refused: %v",
+			from.id, msg.GetReqId(), cfg.GetApplied(), cfg.GetRefused())
+		return
+	}
+	switch msg.GetXType() {
+	// only sent by server
+	case network.Msg_config, network.Msg_reject, network.Msg_ack:
+		logging.Error("drop message %s from %s to %s",
+			msg.GetXType().String(), from.id, to)
+		return
+	}
+	if msg.GetReqId() > 0 {
+		from.sendAck(msg.GetReqId())
+	}
 	if msg.GetXType() == network.Msg_keepalive {
+		if m := msg.GetMetrics(); m != nil {
+			from.setMetrics(m)
+		}
 		return
 	}
 	cli := h.getClient(msg.GetLinkId(), to)
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/lwch/logging"
+	"github.com/lwch/natpass/code/network"
+	"github.com/lwch/natpass/code/utils"
+)
+
+// clientMetrics last metrics reported by client on keepalive
+type clientMetrics struct {
+	at     time.Time
+	links  uint32
+	msgIn  float64 // messages received by client per second
+	msgOut float64 // messages sent by client per second
+	rtt    time.Duration
+}
+
+func (c *client) setMetrics(m *network.KeepaliveMetrics) {
+	var cm clientMetrics
+	cm.at = time.Now()
+	cm.links = m.GetLinks()
+	if m.GetInterval() > 0 {
+		cm.msgIn = float64(m.GetMsgIn()) / float64(m.GetInterval())
+		cm.msgOut = float64(m.GetMsgOut()) / float64(m.GetInterval())
+	}
+	cm.rtt = time.Duration(m.GetRtt()) * time.Millisecond
+	c.Lock()
+	c.metrics = cm
+	c.Unlock()
+}
+
+// Metrics aggregated metrics reported by connected clients on keepalive
+type Metrics struct {
+	Clients int           `json:"clients"` // number of clients reported metrics
+	Links   uint64        `json:"links"`
+	MsgIn   float64       `json:"msg_in"`  // messages received by clients per second
+	MsgOut  float64       `json:"msg_out"` // messages sent by clients per second
+	RTT     time.Duration `json:"rtt"`     // average rtt of clients
+}
+
+// Metrics aggregate metrics reported by connected clients
+func (h *Handler) Metrics() Metrics {
+	h.clis.RLock()
+	list := make([]*client, 0, len(h.clis.data))
+	for _, cli := range h.clis.data {
+		list = append(list, cli)
+	}
+	h.clis.RUnlock()
+	var ret Metrics
+	var rtt time.Duration
+	for _, cli := range list {
+		cli.RLock()
+		m := cli.metrics
+		cli.RUnlock()
+		if m.at.IsZero() {
+			continue
+		}
+		ret.Clients++
+		ret.Links += uint64(m.links)
+		ret.MsgIn += m.msgIn
+		ret.MsgOut += m.msgOut
+		rtt += m.rtt
+	}
+	if ret.Clients > 0 {
+		ret.RTT = rtt / time.Duration(ret.Clients)
+	}
+	return ret
+}
+
+func (h *Handler) logMetrics() {
+	defer utils.Recover("log metrics")
+	for {
+		time.Sleep(h.cfg.MetricsLog)
+		m := h.Metrics()
+		if m.Clients == 0 {
+			continue
+		}
+		logging.Info("metrics of %d clients: links=%d, msg_in=%.2f/s, msg_out=%.2f/s, rtt=%s",
+			m.Clients, m.Links, m.MsgIn, m.MsgOut, m.RTT)
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"github.com/lwch/logging"
+	"github.com/lwch/natpass/code/network"
+)
+
+// relayDialTimeout timeout of connecting to next hop
+const relayDialTimeout = 10 * time.Second
+
+// relay connect to next hop requested by client and splice the connections,
+// the client continues the handshake with next hop on the spliced connection
+func (h *Handler) relay(c *network.Conn, msg *network.Msg) {
+	id := msg.GetFrom()
+	next := msg.GetHsp().GetRelay()
+	if !h.relayAllowed(next) {
+		logging.Error("relay from %s to %s is not allowed", id, next)
+		reject(c, id, network.RejectRelay, h.cfg.WriteTimeout)
+		return
+	}
+	dial, err := net.DialTimeout("tcp", next, relayDialTimeout)
+	if err != nil {
+		logging.Error("relay from %s to %s: %v", id, next, err)
+		reject(c, id, network.RejectRelay, h.cfg.WriteTimeout)
+		return
+	}
+	defer dial.Close()
+
+	var ack network.Msg
+	ack.From = "server"
+	ack.To = id
+	ack.XType = network.Msg_ack
+	ack.ReqId = msg.GetReqId()
+	err = c.WriteMessage(&ack, h.cfg.WriteTimeout)
+	if err != nil {
+		logging.Error("send relay ack to %s: %v", id, err)
+		return
+	}
+	raw := c.Detach()
+	logging.Info("relay %s from %s to %s", id, raw.RemoteAddr().String(), next)
+	go func() {
+		io.Copy(dial, raw)
+		dial.Close()
+	}()
+	io.Copy(raw, dial)
+	logging.Info("relay %s to %s closed", id, next)
+}
+
+func (h *Handler) relayAllowed(next string) bool {
+	if !h.cfg.Relay || len(next) == 0 {
+		return false
+	}
+	if len(h.cfg.RelayAllow) == 0 {
+		return true
+	}
+	for _, addr := range h.cfg.RelayAllow {
+		if addr == next {
+			return true
+		}
+	}
+	return false
+}
@@ -133,6 +133,9 @@ func main() {
 
 func run(cfg *global.Configure, l net.Listener) {
 	h := handler.New(cfg)
+	if cfg.WSListen > 0 {
+		go runWebSocket(cfg, h)
+	}
 	for {
 		conn, err := l.Accept()
 		if err != nil {
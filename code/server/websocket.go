@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/lwch/logging"
+	"github.com/lwch/natpass/code/network"
+	"github.com/lwch/natpass/code/server/global"
+	"github.com/lwch/natpass/code/server/handler"
+)
+
+// runWebSocket accept client connections over websocket
+func runWebSocket(cfg *global.Configure, h *handler.Handler) {
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(*http.Request) bool { return true },
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(cfg.WSPath, func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			logging.Error("upgrade websocket from %s: %v", r.RemoteAddr, err)
+			return
+		}
+		h.Handle(network.NewWebSocketConn(ws))
+	})
+	svr := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.WSListen),
+		Handler: mux,
+	}
+	logging.Info("websocket listen on %d", cfg.WSListen)
+	var err error
+	if len(cfg.TLSCrt) > 0 && len(cfg.TLSKey) > 0 {
+		err = svr.ListenAndServeTLS(cfg.TLSCrt, cfg.TLSKey)
+	} else {
+		err = svr.ListenAndServe()
+	}
+	logging.Error("websocket server: %v", err)
+}